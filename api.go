@@ -0,0 +1,243 @@
+package chainparse
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/trace"
+)
+
+// APIChain is a chain as served by /v1/chains: its ChainSchema, of which
+// only ChainName is set if it was skipped or failed, along with its Outcome.
+type APIChain struct {
+	*ChainSchema
+	Outcome *Outcome `json:"outcome,omitempty"`
+}
+
+// APIChainList is the response of /v1/chains.
+type APIChainList struct {
+	Chains []*APIChain `json:"chains"`
+
+	// Total is the number of chains matching the filters, across all pages.
+	Total int `json:"total"`
+
+	// NextCursor is the cursor query parameter of the next page, if any.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// APIVersions is the response of /v1/chains/{chain_name}/versions.
+type APIVersions struct {
+	ChainName          string         `json:"chain_name"`
+	RecommendedVersion string         `json:"recommended_version,omitempty"`
+	CompatibleVersions []string       `json:"compatible_versions,omitempty"`
+	Versions           []*VersionInfo `json:"versions"`
+}
+
+// APIModule is one of the tracked dependencies of a chain.
+type APIModule struct {
+	// Module is cosmos-sdk, tendermint or ibc-go.
+	Module     string `json:"module"`
+	Version    string `json:"version,omitempty"`
+	ReplacedBy string `json:"replaced_by,omitempty"`
+	Forked     bool   `json:"forked,omitempty"`
+
+	// LatestVersion is the version of the chain's latest analysis, see WithLatest.
+	LatestVersion string `json:"latest_version,omitempty"`
+}
+
+// APIModules is the response of /v1/chains/{chain_name}/modules.
+type APIModules struct {
+	ChainName string       `json:"chain_name"`
+	Modules   []*APIModule `json:"modules"`
+
+	// GoModule is the whole go.mod, if retained with WithGoModules.
+	GoModule *GoModule `json:"go_module,omitempty"`
+}
+
+// apiChains returns every chain of the registry sorted by chain_name,
+// including those that were skipped or failed.
+func apiChains(res *Result) []*APIChain {
+	byName := make(map[string]*APIChain, len(res.Outcomes))
+	var chains []*APIChain
+	for _, out := range res.Outcomes {
+		ac := &APIChain{ChainSchema: &ChainSchema{ChainName: out.ChainName}, Outcome: out}
+		byName[out.ChainName] = ac
+		chains = append(chains, ac)
+	}
+	for _, cs := range res.Chains {
+		if ac := byName[cs.ChainName]; ac != nil {
+			ac.ChainSchema = cs
+			continue
+		}
+		chains = append(chains, &APIChain{ChainSchema: cs})
+	}
+	sort.SliceStable(chains, func(i, j int) bool {
+		return chains[i].ChainName < chains[j].ChainName
+	})
+	return chains
+}
+
+// analysed reports whether the chain's go.mod was analysed, so that it has
+// versions and modules to report.
+func (ac *APIChain) analysed() bool {
+	return ac.Outcome == nil || ac.Outcome.Status == OutcomeOK
+}
+
+func apiVersions(cs *ChainSchema) *APIVersions {
+	av := &APIVersions{ChainName: cs.ChainName, Versions: cs.Versions}
+	if cs.Codebase != nil {
+		av.RecommendedVersion = cs.Codebase.RecommendedVersion
+		av.CompatibleVersions = cs.Codebase.CompatibleVersions
+	}
+	if len(av.Versions) == 0 {
+		// Only the recommended version was analysed.
+		av.Versions = []*VersionInfo{{
+			Version:           av.RecommendedVersion,
+			Recommended:       true,
+			Resolved:          cs.Resolved,
+			CosmosSDKVersion:  cs.CosmosSDKVersion,
+			TendermintVersion: cs.TendermintVersion,
+			IBCVersion:        cs.IBCVersion,
+		}}
+	}
+	return av
+}
+
+func apiModules(cs *ChainSchema) *APIModules {
+	am := &APIModules{ChainName: cs.ChainName, GoModule: cs.GoModule}
+	latest := new(ChainSchema)
+	if cs.Latest != nil {
+		latest = cs.Latest
+	}
+	for _, dep := range []struct{ module, version, latest string }{
+		{moduleCosmosSDK, cs.CosmosSDKVersion, latest.CosmosSDKVersion},
+		{moduleTendermint, cs.TendermintVersion, latest.TendermintVersion},
+		{moduleIBC, cs.IBCVersion, latest.IBCVersion},
+	} {
+		version, replacedBy := splitDependency(dep.version)
+		latestVersion, _ := splitDependency(dep.latest)
+		am.Modules = append(am.Modules, &APIModule{
+			Module:        dep.module,
+			Version:       version,
+			ReplacedBy:    replacedBy,
+			Forked:        isForkedDependency(dep.version),
+			LatestVersion: latestVersion,
+		})
+	}
+	return am
+}
+
+// Chains serves the chains of the last snapshot taken, keyed by chain_name:
+//
+//	/v1/chains                           every chain along with its outcome,
+//	                                     see parseChainQuery for its query parameters
+//	/v1/chains/{chain_name}              a single chain
+//	/v1/chains/{chain_name}/versions     its recommended and compatible versions
+//	/v1/chains/{chain_name}/modules      its Cosmos SDK, Tendermint and IBC modules
+//
+// Unknown chains get a 404, as do the versions and modules of chains that
+// failed to be analysed.
+func (cp *ChainParser) Chains(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := trace.StartSpan(req.Context(), "Chains")
+	defer span.End()
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		rw.Header().Set("Allow", "GET, HEAD")
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var chainName, resource string
+	switch rest := strings.TrimPrefix(req.URL.Path, "/v1/chains"); {
+	case rest == "" || rest == "/":
+	case strings.HasPrefix(rest, "/"):
+		parts := strings.Split(rest[1:], "/")
+		if len(parts) > 2 || parts[0] == "" {
+			http.NotFound(rw, req)
+			return
+		}
+		chainName = parts[0]
+		if len(parts) == 2 {
+			if resource = parts[1]; resource != "versions" && resource != "modules" {
+				http.NotFound(rw, req)
+				return
+			}
+		}
+	default:
+		http.NotFound(rw, req)
+		return
+	}
+
+	var cq *chainQuery
+	if chainName == "" {
+		var err error
+		if cq, err = parseChainQuery(req.URL.Query()); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	snap, err := cp.fetcher.snapshot(ctx)
+	if err != nil {
+		logrus.WithContext(ctx).WithError(err).Error("failed to retrieve all chain schema")
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Encode sorts the query parameters, so that their order doesn't matter.
+	key := req.URL.Path
+	if cq != nil {
+		key += "?" + req.URL.Query().Encode()
+	}
+	if resp := snap.cachedResponse(key); resp != nil {
+		resp.serve(rw, req, snap)
+		return
+	}
+
+	chains := apiChains(snap.res)
+	var body interface{}
+	if cq != nil {
+		page, total, next, err := cq.apply(chains)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if page == nil {
+			page = []*APIChain{}
+		}
+		body = &APIChainList{Chains: page, Total: total, NextCursor: next}
+	} else {
+		var chain *APIChain
+		for _, ac := range chains {
+			if ac.ChainName == chainName {
+				chain = ac
+				break
+			}
+		}
+		switch {
+		case chain == nil:
+			http.Error(rw, fmt.Sprintf("unknown chain %q", chainName), http.StatusNotFound)
+			return
+		case resource == "":
+			body = chain
+		case !chain.analysed():
+			http.Error(rw, fmt.Sprintf("chain %q has no %s, its outcome is %q", chainName, resource, chain.Outcome.Status), http.StatusNotFound)
+			return
+		case resource == "versions":
+			body = apiVersions(chain.ChainSchema)
+		case resource == "modules":
+			body = apiModules(chain.ChainSchema)
+		}
+	}
+
+	blob, err := json.Marshal(body)
+	if err != nil {
+		logrus.WithContext(ctx).WithError(err).Error("failed to JSON marshal the chains")
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	snap.cacheResponse(key, rw.Header(), append(blob, '\n')).serve(rw, req, snap)
+}
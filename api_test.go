@@ -0,0 +1,118 @@
+package chainparse
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func apiChainParser() *ChainParser {
+	cp := NewChainParser(nil)
+	cp.fetcher.snap = &snapshot{res: reportResult, at: reportTime}
+	return cp
+}
+
+func TestChainsList(t *testing.T) {
+	rec := httptest.NewRecorder()
+	apiChainParser().Chains(rec, httptest.NewRequest("GET", "/v1/chains", nil))
+	if g, w := rec.Code, http.StatusOK; g != w {
+		t.Fatalf("Status: got %d, want %d", g, w)
+	}
+	if g, w := rec.Header().Get("Last-Modified"), "Sat, 01 Oct 2022 12:00:00 GMT"; g != w {
+		t.Errorf("Last-Modified: got %q, want %q", g, w)
+	}
+	var list struct {
+		Chains []struct {
+			ChainName  string   `json:"chain_name"`
+			PrettyName string   `json:"pretty_name"`
+			Outcome    *Outcome `json:"outcome"`
+		} `json:"chains"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, ac := range list.Chains {
+		names = append(names, ac.ChainName+":"+string(ac.Outcome.Status))
+	}
+	want := []string{"agoric:ok", "aioz:failed", "cosmoshub:ok", "theta:ok"}
+	if diff := cmp.Diff(names, want); diff != "" {
+		t.Errorf("Chains mismatch: got - want +\n%s", diff)
+	}
+	if g, w := list.Chains[2].PrettyName, "Cosmos | Hub"; g != w {
+		t.Errorf("PrettyName: got %q, want %q", g, w)
+	}
+}
+
+func TestChainsResources(t *testing.T) {
+	cp := apiChainParser()
+	tests := []struct {
+		path       string
+		wantStatus int
+		got, want  interface{}
+	}{
+		{
+			path:       "/v1/chains/cosmoshub/versions",
+			wantStatus: http.StatusOK,
+			got:        new(APIVersions),
+			want: &APIVersions{
+				ChainName:          "cosmoshub",
+				RecommendedVersion: "v7.0.0",
+				Versions: []*VersionInfo{{
+					Version:           "v7.0.0",
+					Recommended:       true,
+					CosmosSDKVersion:  "v0.45.4",
+					TendermintVersion: "v0.34.19",
+					IBCVersion:        "v3.0.0@github.com/cosmos/ibc-go/v3",
+				}},
+			},
+		},
+		{
+			path:       "/v1/chains/agoric/modules",
+			wantStatus: http.StatusOK,
+			got:        new(APIModules),
+			want: &APIModules{
+				ChainName: "agoric",
+				Modules: []*APIModule{
+					{Module: "cosmos-sdk", Version: "v0.44.2-alpha.agoric.gaiad.1", ReplacedBy: "github.com/agoric-labs/cosmos-sdk", Forked: true},
+					{Module: "tendermint", Version: "v0.34.13", ReplacedBy: "github.com/tendermint/tendermint"},
+					{Module: "ibc-go", Version: "v1.2.0"},
+				},
+			},
+		},
+		{path: "/v1/chains/theta", wantStatus: http.StatusOK},
+		{path: "/v1/chains/theta/", wantStatus: http.StatusNotFound},
+		{path: "/v1/chains/osmosis", wantStatus: http.StatusNotFound},
+		{path: "/v1/chains/osmosis/modules", wantStatus: http.StatusNotFound},
+		{path: "/v1/chains/aioz", wantStatus: http.StatusOK},
+		{path: "/v1/chains/aioz/versions", wantStatus: http.StatusNotFound},
+		{path: "/v1/chains/cosmoshub/releases", wantStatus: http.StatusNotFound},
+		{path: "/v1/chainsx", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		cp.Chains(rec, httptest.NewRequest("GET", tt.path, nil))
+		if g, w := rec.Code, tt.wantStatus; g != w {
+			t.Errorf("%s: status: got %d, want %d", tt.path, g, w)
+			continue
+		}
+		if tt.want == nil {
+			continue
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), tt.got); err != nil {
+			t.Fatalf("%s: %v", tt.path, err)
+		}
+		if diff := cmp.Diff(tt.got, tt.want); diff != "" {
+			t.Errorf("%s: mismatch: got - want +\n%s", tt.path, diff)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	cp.Chains(rec, httptest.NewRequest("DELETE", "/v1/chains/theta", nil))
+	if g, w := rec.Code, http.StatusMethodNotAllowed; g != w {
+		t.Errorf("DELETE: status: got %d, want %d", g, w)
+	}
+}
@@ -0,0 +1,187 @@
+package chainparse
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/trace"
+	"golang.org/x/mod/semver"
+)
+
+// The colours of badges, as named by shields.io.
+const (
+	badgeUpToDate     = "#4c1"    // brightgreen: on the newest release line
+	badgeMinorBehind  = "#dfb317" // yellow: one minor release line behind
+	badgeMinorsBehind = "#fe7d37" // orange: several minor release lines behind
+	badgeMajorBehind  = "#e05d44" // red: a major release line behind
+	badgeUnknown      = "#9f9f9f" // lightgrey: no version to compare
+)
+
+// badgeModules maps the module names of badge URLs to the tracked
+// dependencies, as keyed in Report.Newest.
+var badgeModules = map[string]string{
+	moduleCosmosSDK:  moduleCosmosSDK,
+	moduleTendermint: moduleTendermint,
+	"cometbft":       moduleTendermint,
+	moduleIBC:        moduleIBC,
+}
+
+// badgeSupportedLines are the newest release lines that upstream supports of
+// the tracked dependencies, which badges are coloured against so that they
+// don't all turn green while no chain has moved to the newest line yet. They
+// need updating as upstream releases new lines, though a chain already on a
+// newer line than listed here is taken as the newest.
+var badgeSupportedLines = map[string]string{
+	moduleCosmosSDK:  "v0.53",
+	moduleTendermint: "v0.38",
+	moduleIBC:        "v10.0",
+}
+
+// badgeNewest returns the release line of module that badges are coloured
+// against, given the newest line in use amongst the chains.
+func badgeNewest(module, inUse string) string {
+	newest := badgeSupportedLines[module]
+	if semver.Compare(inUse, newest) > 0 {
+		return inUse
+	}
+	return newest
+}
+
+// badgeColor returns the colour of the badge of a dependency on the version
+// vers when newest is the newest major.minor of the dependency, see badgeNewest.
+func badgeColor(vers, newest string) string {
+	mm := semver.MajorMinor(vers)
+	if mm == "" || newest == "" {
+		return badgeUnknown
+	}
+	if semver.Major(mm) != semver.Major(newest) {
+		if semver.Compare(mm, newest) < 0 {
+			return badgeMajorBehind
+		}
+		return badgeUpToDate
+	}
+	minor := func(mm string) int {
+		n, _ := strconv.Atoi(mm[strings.IndexByte(mm, '.')+1:])
+		return n
+	}
+	switch behind := minor(newest) - minor(mm); {
+	case behind <= 0:
+		return badgeUpToDate
+	case behind == 1:
+		return badgeMinorBehind
+	default:
+		return badgeMinorsBehind
+	}
+}
+
+// badgeTextWidth approximates the width in pixels of s in 11px Verdana.
+func badgeTextWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		switch {
+		case strings.ContainsRune("ijlt.,:;|!'()[]/ ", r):
+			width += 4
+		case strings.ContainsRune("mwMW@", r):
+			width += 10
+		default:
+			width += 7
+		}
+	}
+	return width
+}
+
+// writeBadge writes a shields.io style flat badge.
+func writeBadge(w io.Writer, label, message, color string) error {
+	lw, mw := badgeTextWidth(label)+10, badgeTextWidth(message)+10
+	label, message = xmlEscape(label), xmlEscape(message)
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">
+<title>%[4]s: %[5]s</title>
+<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)"><rect width="%[2]d" height="20" fill="#555"/><rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/><rect width="%[1]d" height="20" fill="url(#s)"/></g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%[7]d" y="15" fill="#010101" fill-opacity=".3">%[4]s</text><text x="%[7]d" y="14">%[4]s</text>
+<text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[5]s</text><text x="%[8]d" y="14">%[5]s</text>
+</g>
+</svg>
+`, lw+mw, lw, mw, label, message, color, lw/2, lw+mw/2)
+	return err
+}
+
+// Badge serves /badge/{chain_name}/{module}.svg, a badge of the version of
+// cosmos-sdk, tendermint (or cometbft) or ibc-go that the chain's recommended
+// version is built on, coloured by how many release lines it's behind the
+// newest one supported upstream, see badgeNewest. It serves the last snapshot
+// taken, rendering each badge once per snapshot, see snapshotResponse.
+func (cp *ChainParser) Badge(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := trace.StartSpan(req.Context(), "Badge")
+	defer span.End()
+
+	parts := strings.Split(strings.TrimPrefix(req.URL.Path, "/badge/"), "/")
+	if len(parts) != 2 || !strings.HasSuffix(parts[1], ".svg") {
+		http.NotFound(rw, req)
+		return
+	}
+	chainName, module := parts[0], strings.TrimSuffix(parts[1], ".svg")
+	depModule, ok := badgeModules[module]
+	if !ok {
+		http.Error(rw, fmt.Sprintf("unknown module %q, expected cosmos-sdk, tendermint, cometbft or ibc-go", module), http.StatusNotFound)
+		return
+	}
+
+	snap, err := cp.fetcher.snapshot(ctx)
+	if err != nil {
+		logrus.WithContext(ctx).WithError(err).Error("failed to retrieve all chain schema")
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", "image/svg+xml")
+	rw.Header().Set("Cache-Control", "max-age=300")
+	if resp := snap.cachedResponse(req.URL.Path); resp != nil {
+		resp.serve(rw, req, snap)
+		return
+	}
+
+	rep := snap.report()
+	var row *ReportRow
+	for _, r := range append(rep.Mainnets, rep.Testnets...) {
+		if r.Chain.ChainName == chainName {
+			row = r
+			break
+		}
+	}
+	if row == nil {
+		snap.setHeaders(rw, time.Now())
+		rw.WriteHeader(http.StatusNotFound)
+		if err := writeBadge(rw, module, "unknown chain", badgeUnknown); err != nil {
+			logrus.WithContext(ctx).WithError(err).Error("failed to send the badge")
+		}
+		return
+	}
+
+	dep := map[string]ReportDependency{
+		moduleCosmosSDK:  row.CosmosSDK,
+		moduleTendermint: row.Tendermint,
+		moduleIBC:        row.IBC,
+	}[depModule]
+	message := dep.Version
+	if message == "" {
+		message = "none"
+	}
+	if dep.Forked {
+		message += " (fork)"
+	}
+	var body bytes.Buffer
+	if err := writeBadge(&body, module, message, badgeColor(dep.Version, badgeNewest(depModule, rep.Newest[depModule]))); err != nil {
+		logrus.WithContext(ctx).WithError(err).Error("failed to render the badge")
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	snap.cacheResponse(req.URL.Path, rw.Header(), body.Bytes()).serve(rw, req, snap)
+}
@@ -0,0 +1,112 @@
+package chainparse
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBadgeColor(t *testing.T) {
+	tests := []struct {
+		vers, newest string
+		want         string
+	}{
+		{"v0.47.2", "v0.47", badgeUpToDate},
+		{"v0.46.13", "v0.47", badgeMinorBehind},
+		{"v0.45.4", "v0.47", badgeMinorsBehind},
+		{"v0.38.0", "v1.0", badgeMajorBehind},
+		{"v7.1.0", "v7.1", badgeUpToDate},
+		{"", "v0.47", badgeUnknown},
+		{"agoric-3.1", "v0.47", badgeUnknown},
+	}
+	for _, tt := range tests {
+		if got := badgeColor(tt.vers, tt.newest); got != tt.want {
+			t.Errorf("badgeColor(%q, %q): got %q, want %q", tt.vers, tt.newest, got, tt.want)
+		}
+	}
+}
+
+func TestBadgeNewest(t *testing.T) {
+	if g, w := badgeNewest(moduleCosmosSDK, "v0.47"), "v0.53"; g != w {
+		t.Errorf("No chain on the supported line: got %q, want %q", g, w)
+	}
+	if g, w := badgeNewest(moduleCosmosSDK, "v0.54"), "v0.54"; g != w {
+		t.Errorf("A chain on a newer line: got %q, want %q", g, w)
+	}
+	if g, w := badgeNewest(moduleIBC, ""), "v10.0"; g != w {
+		t.Errorf("No chain using ibc-go: got %q, want %q", g, w)
+	}
+}
+
+func TestBadge(t *testing.T) {
+	res := &Result{Chains: append(append([]*ChainSchema(nil), reportResult.Chains...), &ChainSchema{
+		ChainName:         "cometchain",
+		NetworkType:       "mainnet",
+		CosmosSDKVersion:  "v0.50.4",
+		TendermintVersion: "v0.38.5@github.com/cometbft/cometbft",
+		IBCVersion:        "v8.1.0",
+	})}
+	cp := NewChainParser(nil)
+	cp.fetcher.snap = &snapshot{res: res, at: reportTime}
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantColor  string
+		wantText   string
+	}{
+		{"/badge/agoric/cosmos-sdk.svg", http.StatusOK, badgeMinorsBehind, "v0.44.2-alpha.agoric.gaiad.1 (fork)"},
+		{"/badge/cosmoshub/ibc-go.svg", http.StatusOK, badgeMajorBehind, "v3.0.0"},
+		{"/badge/cosmoshub/cometbft.svg", http.StatusOK, badgeMinorsBehind, "v0.34.19"},
+		{"/badge/cometchain/cometbft.svg", http.StatusOK, badgeUpToDate, "v0.38.5"},
+		{"/badge/cometchain/tendermint.svg", http.StatusOK, badgeUpToDate, "v0.38.5"},
+		{"/badge/cometchain/cosmos-sdk.svg", http.StatusOK, badgeMinorsBehind, "v0.50.4"},
+		{"/badge/theta/tendermint.svg", http.StatusOK, badgeUnknown, "none"},
+		{"/badge/aioz/cosmos-sdk.svg", http.StatusNotFound, badgeUnknown, "unknown chain"},
+		{"/badge/cosmoshub/wasmd.svg", http.StatusNotFound, "", ""},
+		{"/badge/cosmoshub/ibc-go.png", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		cp.Badge(rec, httptest.NewRequest("GET", tt.path, nil))
+		if g, w := rec.Code, tt.wantStatus; g != w {
+			t.Errorf("%s: status: got %d, want %d", tt.path, g, w)
+		}
+		if tt.wantColor == "" {
+			continue
+		}
+		if g, w := rec.Header().Get("Content-Type"), "image/svg+xml"; g != w {
+			t.Errorf("%s: Content-Type: got %q, want %q", tt.path, g, w)
+		}
+		body := rec.Body.String()
+		if err := xml.Unmarshal([]byte(body), new(struct{})); err != nil {
+			t.Errorf("%s: invalid SVG: %v\n%s", tt.path, err, body)
+		}
+		if !strings.Contains(body, `fill="`+tt.wantColor+`"`) {
+			t.Errorf("%s: badge isn't coloured %s:\n%s", tt.path, tt.wantColor, body)
+		}
+		if !strings.Contains(body, ">"+tt.wantText+"</text>") {
+			t.Errorf("%s: badge doesn't read %q:\n%s", tt.path, tt.wantText, body)
+		}
+	}
+	// Badges are rendered once per snapshot, with an ETag.
+	const path = "/badge/cometchain/cometbft.svg"
+	if cp.fetcher.snap.cachedResponse(path) == nil {
+		t.Fatalf("%s: the badge wasn't cached", path)
+	}
+	rec := httptest.NewRecorder()
+	cp.Badge(rec, httptest.NewRequest("GET", path, nil))
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("%s: no ETag", path)
+	}
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	cp.Badge(rec, req)
+	if g, w := rec.Code, http.StatusNotModified; g != w {
+		t.Errorf("%s: status with If-None-Match: got %d, want %d", path, g, w)
+	}
+}
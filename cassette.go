@@ -0,0 +1,232 @@
+package chainparse
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// A Cassette holds the HTTP interactions of a run so that the
+// run can be replayed offline, e.g. to reproduce a bug report.
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interaction is a single recorded request and the response it got.
+type Interaction struct {
+	Request  *RecordedRequest  `json:"request"`
+	Response *RecordedResponse `json:"response"`
+}
+
+// RecordedRequest identifies a request. Request headers aren't recorded,
+// so credentials such as a GitHub token never end up in a cassette.
+type RecordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+
+	// BodySHA256 is the hex encoded SHA-256 of the request body, if any,
+	// which tells apart the different GraphQL queries sent to the same URL.
+	BodySHA256 string `json:"body_sha256,omitempty"`
+}
+
+// RecordedResponse is a response as it was received, its body byte for byte.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       []byte      `json:"body,omitempty"`
+}
+
+func (rr *RecordedRequest) key() string {
+	return rr.Method + " " + rr.URL + " " + rr.BodySHA256
+}
+
+// LoadCassette reads a cassette written by Cassette.Save.
+func LoadCassette(path string) (*Cassette, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := new(Cassette)
+	if err := json.Unmarshal(blob, c); err != nil {
+		return nil, fmt.Errorf("decoding cassette %q: %w", path, err)
+	}
+	return c, nil
+}
+
+// Save writes the cassette to path as JSON.
+func (c *Cassette) Save(path string) error {
+	blob, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, blob, 0644)
+}
+
+// recordRequest consumes the body of req, if any, so it has to be restored by the caller.
+func recordRequest(req *http.Request) (*RecordedRequest, []byte, error) {
+	rr := &RecordedRequest{Method: req.Method, URL: req.URL.String()}
+	if req.Body == nil || req.Body == http.NoBody {
+		return rr, nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(body) != 0 {
+		sum := sha256.Sum256(body)
+		rr.BodySHA256 = hex.EncodeToString(sum[:])
+	}
+	return rr, body, nil
+}
+
+// Recorder is an http.RoundTripper that records every interaction
+// that goes through it into a Cassette.
+type Recorder struct {
+	next http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+var _ http.RoundTripper = (*Recorder)(nil)
+
+// NewRecorder returns a Recorder that sends requests with next,
+// or with http.DefaultTransport if next is nil.
+func NewRecorder(next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{next: next}
+}
+
+func (rec *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	rr, reqBody, err := recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if reqBody != nil {
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+		req.ContentLength = int64(len(reqBody))
+	}
+
+	res, err := rec.next.RoundTrip(req)
+	if err != nil {
+		// Failures to get a response aren't recorded and so
+		// will show up as unexpected requests when replaying.
+		return nil, err
+	}
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	rec.mu.Lock()
+	rec.cassette.Interactions = append(rec.cassette.Interactions, &Interaction{
+		Request: rr,
+		Response: &RecordedResponse{
+			StatusCode: res.StatusCode,
+			Header:     res.Header.Clone(),
+			Body:       body,
+		},
+	})
+	rec.mu.Unlock()
+	return res, nil
+}
+
+// Cassette returns a copy of the interactions recorded so far.
+func (rec *Recorder) Cassette() *Cassette {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return &Cassette{Interactions: append([]*Interaction(nil), rec.cassette.Interactions...)}
+}
+
+// Save writes the interactions recorded so far to path.
+func (rec *Recorder) Save(path string) error {
+	return rec.Cassette().Save(path)
+}
+
+// Reset discards the interactions recorded so far, such as once a
+// long-running server has saved those of a refresh.
+func (rec *Recorder) Reset() {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.cassette.Interactions = nil
+}
+
+// Replayer is an http.RoundTripper that answers requests from a Cassette
+// without touching the network. Requests are matched by method, URL and
+// body; identical requests are answered in the order they were recorded,
+// the last answer being repeated once they run out. A request that wasn't
+// recorded fails.
+type Replayer struct {
+	mu      sync.Mutex
+	pending map[string][]*RecordedResponse
+}
+
+var _ http.RoundTripper = (*Replayer)(nil)
+
+// NewReplayer returns a Replayer that serves the interactions of c.
+func NewReplayer(c *Cassette) *Replayer {
+	pending := make(map[string][]*RecordedResponse)
+	for _, it := range c.Interactions {
+		key := it.Request.key()
+		pending[key] = append(pending[key], it.Response)
+	}
+	return &Replayer{pending: pending}
+}
+
+// UnexpectedRequestError is returned by Replayer for requests that aren't in its cassette.
+type UnexpectedRequestError struct {
+	Method string
+	URL    string
+}
+
+func (eur *UnexpectedRequestError) Error() string {
+	return fmt.Sprintf("replay: no recorded interaction for %s %s", eur.Method, eur.URL)
+}
+
+func (rep *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	rr, _, err := recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	rep.mu.Lock()
+	responses := rep.pending[rr.key()]
+	if len(responses) == 0 {
+		rep.mu.Unlock()
+		return nil, &UnexpectedRequestError{Method: rr.Method, URL: rr.URL}
+	}
+	recorded := responses[0]
+	if len(responses) > 1 {
+		rep.pending[rr.key()] = responses[1:]
+	}
+	rep.mu.Unlock()
+
+	header := recorded.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(recorded.StatusCode) + " " + http.StatusText(recorded.StatusCode),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,76 @@
+package chainparse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRecordReplay(t *testing.T) {
+	cst := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch p := req.URL.Path; {
+		case strings.HasPrefix(p, "/AIOZNetwork/"):
+			http.NotFound(rw, req)
+		case strings.HasSuffix(p, "go.mod"):
+			rw.Write(testdataGoMod)
+		default:
+			rw.Write(testdataZip)
+		}
+	}))
+
+	destURL, err := url.Parse(cst.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := NewRecorder(&alwaysToURLRoundTripper{next: cst.Client(), destURL: destURL})
+	ctx := context.Background()
+	recorded, err := newFetcher(rec).fetchResult(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cst.Close()
+
+	cassettePath := filepath.Join(t.TempDir(), "cassette.json")
+	if err := rec.Save(cassettePath); err != nil {
+		t.Fatal(err)
+	}
+	cassette, err := LoadCassette(cassettePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, it := range cassette.Interactions {
+		if strings.Contains(it.Request.URL, destURL.Host) {
+			t.Fatalf("The original URL should have been recorded, got %q", it.Request.URL)
+		}
+	}
+
+	// The test server is gone so everything has to come from the cassette.
+	replayed, err := newFetcher(NewReplayer(cassette)).fetchResult(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(replayed, recorded); diff != "" {
+		t.Fatalf("Replay mismatch: got - want +\n%s", diff)
+	}
+
+	client := &http.Client{Transport: NewReplayer(cassette)}
+	_, err = client.Get("https://example.com/not-recorded")
+	var uerr *UnexpectedRequestError
+	if !errors.As(err, &uerr) {
+		t.Fatalf("Expected an UnexpectedRequestError, got %v", err)
+	}
+	if g, w := uerr.URL, "https://example.com/not-recorded"; g != w {
+		t.Errorf("URL mismatch: got %q, want %q", g, w)
+	}
+	rec.Reset()
+	if g := len(rec.Cassette().Interactions); g != 0 {
+		t.Errorf("Interactions after Reset: got %d, want 0", g)
+	}
+}
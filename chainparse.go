@@ -4,25 +4,23 @@ import (
 	"archive/zip"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"net/http"
-	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"reflect"
 	"regexp"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
-	"github.com/google/go-github/v47/github"
 	"go.opencensus.io/trace"
 	"golang.org/x/mod/modfile"
 	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
 
 	"github.com/sirupsen/logrus"
 )
@@ -49,29 +47,116 @@ type ChainSchema struct {
 	IBCVersion        string    `json:"ibc_version,omitempty"`
 	Contact           string    `json:"contact,omitempty"`
 	AccountManageer   string    `json:"account_mgr,omitempty"`
+	Archived          bool      `json:"archived,omitempty"`
+	LatestRelease     string    `json:"latest_release,omitempty"`
+
+	// Repo is the canonical identity of the codebase's git_repo.
+	Repo *RepoID `json:"repo,omitempty"`
+
+	// Resolved records the ref that recommended_version resolved to,
+	// if it couldn't be used literally.
+	Resolved *RefResolution `json:"resolved,omitempty"`
+
+	// Versions is the version matrix of the recommended and every compatible version.
+	Versions []*VersionInfo `json:"versions,omitempty"`
+
+	// Releases summarizes the versions released by the chain's repository.
+	Releases *ReleaseSummary `json:"releases,omitempty"`
+
+	// GoModule details the analysed go.mod file, see WithGoModules.
+	GoModule *GoModule `json:"go_module,omitempty"`
 
 	Latest *ChainSchema `json:"latest,omitempty"`
 }
 
 type fetcher struct {
-	rt http.RoundTripper
+	rt          http.RoundTripper
+	githubToken string
+
+	latest    LatestStrategy
+	latestSem chan struct{}
+
+	compatibleVersions bool
+	releaseSources     []ReleaseSource
 
+	progress  func(*ChainSchema, *Outcome)
+	goModules bool
+	goSums    bool
+	templates map[string]*Template
+
+	metrics *fetchMetrics
+	changes *ChangeLog // guarded by mu
+
+	// changesPath is where changes is saved after every refresh, if anywhere.
+	changesPath string
+
+	snap     *snapshot     // guarded by mu
+	snapCall *snapshotCall // guarded by mu
+
+	// The caches are reset by every refresh, see resetCaches.
 	mu        sync.Mutex
-	repoCache map[string]*github.Repository
+	repoCache map[string]*githubRepo
+	refsCache map[string]*gitRefs
+	modCache  map[string][]byte
 }
 
-func newFetcher(rt http.RoundTripper) *fetcher {
-	return &fetcher{
-		rt: rt,
+func newFetcher(rt http.RoundTripper, opts ...Option) *fetcher {
+	metrics := newFetchMetrics()
+	fr := &fetcher{
+		rt: &rateLimitTransport{next: rt, metrics: metrics},
 
-		repoCache: make(map[string]*github.Repository),
+		metrics:   metrics,
+		changes:   new(ChangeLog),
+		latestSem: make(chan struct{}, DefaultLatestConcurrency),
+	}
+	fr.resetCaches()
+	for _, opt := range opts {
+		opt(fr)
 	}
+	return fr
+}
+
+// resetCaches empties the caches of repositories, refs and go.mod files,
+// which only hold for a single refresh since branches and tags move.
+func (fr *fetcher) resetCaches() {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.repoCache = make(map[string]*githubRepo)
+	fr.refsCache = make(map[string]*gitRefs)
+	fr.modCache = make(map[string][]byte)
 }
 
 func (fr *fetcher) fetchChainData(ctx context.Context) ([]*ChainSchema, error) {
+	res, err := fr.fetchResult(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return res.Chains, nil
+}
+
+func (fr *fetcher) fetchResult(ctx context.Context) (*Result, error) {
 	ctx, span := trace.StartSpan(ctx, "fetchChainData")
 	defer span.End()
 
+	start := time.Now()
+	res, err := fr.refresh(ctx)
+	fr.metrics.recordRefresh(res, time.Now(), time.Since(start), err)
+	if err == nil {
+		fr.mu.Lock()
+		fr.changes.Record(res, time.Now())
+		if fr.changesPath != "" {
+			if err := fr.changes.Save(fr.changesPath); err != nil {
+				logrus.WithContext(ctx).WithError(err).Error("failed to save the change log")
+			}
+		}
+		fr.mu.Unlock()
+	}
+	return res, err
+}
+
+func (fr *fetcher) refresh(ctx context.Context) (*Result, error) {
+	fr.resetCaches()
+
 	registryDir, err := os.MkdirTemp(os.TempDir(), "registry")
 	if err != nil {
 		return nil, err
@@ -127,10 +212,17 @@ func extractCosmosTuplesByVersion(modSrcs []module.Version, isReplaceDirective b
 			// For replace directives we want to append the replaced version with the URL.
 			suffix = "@" + mod.Path
 		}
-		switch modPath := mod.Path; {
+		modPath := mod.Path
+		// Strip any major version suffix such as the /v7 of ibc-go.
+		if i := strings.LastIndexByte(modPath, '/'); i >= 0 && semver.IsValid(modPath[i+1:]) {
+			modPath = modPath[:i]
+		}
+		switch {
 		case strings.HasSuffix(modPath, "cosmos-sdk"):
 			cosmosSDKVers = mod.Version + suffix
-		case strings.HasSuffix(modPath, "tendermint"):
+		case strings.HasSuffix(modPath, "tendermint"), strings.HasSuffix(modPath, "cometbft"):
+			// CometBFT is Tendermint's successor, which chains either
+			// require directly or replace Tendermint with.
 			tendermintVers = mod.Version + suffix
 		case strings.HasSuffix(modPath, "ibc-go"):
 			ibcVers = mod.Version + suffix
@@ -139,7 +231,13 @@ func extractCosmosTuplesByVersion(modSrcs []module.Version, isReplaceDirective b
 	return
 }
 
-func (fr *fetcher) findChainJSONFiles(ctx context.Context, registryDir string) (csL []*ChainSchema, rerr error) {
+// registryEntry is a chain.json file from the registry.
+type registryEntry struct {
+	path string
+	cs   *ChainSchema
+}
+
+func (fr *fetcher) findChainJSONFiles(ctx context.Context, registryDir string) (entries []*registryEntry, skipped []*Outcome, rerr error) {
 	ctx, span := trace.StartSpan(ctx, "findChainJSONFiles")
 	defer span.End()
 
@@ -159,74 +257,128 @@ func (fr *fetcher) findChainJSONFiles(ctx context.Context, registryDir string) (
 		defer f.Close()
 
 		blob, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
 		cs := new(ChainSchema)
 		if err := json.Unmarshal(blob, cs); err != nil {
-			return err
+			// A single malformed chain.json shouldn't prevent reporting on the rest.
+			chainName := filepath.Base(filepath.Dir(path))
+			out := newOutcome(chainName, path, &FetchError{Category: CategoryInvalidChainJSON, Err: err})
+			skipped = append(skipped, out)
+			return nil
 		}
 		if cs.Codebase == nil {
-			logrus.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
+			logrus.WithContext(ctx).WithFields(logrus.Fields{
 				"path": path,
 			}).Error("No codebase")
+			skipped = append(skipped, &Outcome{
+				ChainName: cs.ChainName,
+				Path:      path,
+				Status:    OutcomeSkipped,
+				Category:  CategoryNoCodebase,
+				Message:   "the chain.json has no codebase",
+			})
 		} else {
-			csL = append(csL, cs)
+			entries = append(entries, &registryEntry{path: path, cs: cs})
 		}
 		return nil
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	sort.Slice(csL, func(i, j int) bool {
-		oi, oj := csL[i], csL[j]
+	sort.Slice(entries, func(i, j int) bool {
+		oi, oj := entries[i].cs, entries[j].cs
 		return oi.ChainName < oj.ChainName
 	})
 
-	return csL, nil
+	return entries, skipped, nil
+}
+
+// chainResult is what traverse collects for every chain that was run.
+type chainResult struct {
+	cs  *ChainSchema
+	out *Outcome
 }
 
-func (fr *fetcher) traverse(ctx context.Context, outputDir string) ([]*ChainSchema, error) {
-	inputs, err := fr.findChainJSONFiles(ctx, outputDir)
+func (fr *fetcher) traverse(ctx context.Context, outputDir string) (*Result, error) {
+	entries, skipped, err := fr.findChainJSONFiles(ctx, outputDir)
 	if err != nil {
 		return nil, err
 	}
 
+	seeds := make([]*ChainSchema, 0, len(entries))
+	for _, entry := range entries {
+		seeds = append(seeds, entry.cs)
+	}
+	// Failing to batch lookup the repositories isn't fatal as
+	// each chain can still be fetched individually by run.
+	if err := fr.prefetchGitHubRepos(ctx, seeds); err != nil {
+		logrus.WithContext(ctx).WithError(err).Error("failed to prefetch the GitHub repositories")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	inputCh := make(chan *registryEntry, 10)
+	go func() {
+		defer close(inputCh)
+
+		for _, entry := range entries {
+			inputCh <- entry
+		}
+	}()
+
+	// The WaitGroup must only be waited on after every input
+	// has been added to it, otherwise a registry smaller than
+	// inputCh's buffer closes outputCh before any chain is run.
 	wg := new(sync.WaitGroup)
-	inputCh := make(chan *ChainSchema, 10)
-	outputCh := make(chan *ChainSchema, 1)
+	outputCh := make(chan *chainResult, 1)
 	go func() {
 		defer close(outputCh)
 		defer wg.Wait()
-		defer close(inputCh)
 
-		for _, cs := range inputs {
-			inputCh <- cs
+		for entry := range inputCh {
+			wg.Add(1)
+			go func(entry *registryEntry) {
+				defer wg.Done()
+				cs, warnings, err := fr.run(ctx, *entry.cs)
+				out := newOutcome(entry.cs.ChainName, entry.path, err)
+				out.Warnings = warnings
+				if err != nil {
+					cs = nil
+				}
+				outputCh <- &chainResult{cs: cs, out: out}
+			}(entry)
 		}
 	}()
 
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	for cs := range inputCh {
-		wg.Add(1)
-		go func(cs *ChainSchema) {
-			defer wg.Done()
-			cs, err := fr.run(ctx, *cs)
-			if err == nil && cs != nil {
-				outputCh <- cs
-			}
-		}(cs)
+	res := &Result{
+		Chains:   make([]*ChainSchema, 0, len(entries)),
+		Outcomes: append(make([]*Outcome, 0, len(entries)+len(skipped)), skipped...),
 	}
-
-	output := make([]*ChainSchema, 0, len(inputs))
-	for cs := range outputCh {
-		output = append(output, cs)
+	if fr.progress != nil {
+		for _, out := range skipped {
+			fr.progress(nil, out)
+		}
+	}
+	for cr := range outputCh {
+		if fr.progress != nil {
+			fr.progress(cr.cs, cr.out)
+		}
+		if cr.cs != nil {
+			res.Chains = append(res.Chains, cr.cs)
+		}
+		res.Outcomes = append(res.Outcomes, cr.out)
 	}
 
-	sort.Slice(output, func(i, j int) bool {
-		oi, oj := output[i], output[j]
+	sort.Slice(res.Chains, func(i, j int) bool {
+		oi, oj := res.Chains[i], res.Chains[j]
 		return oi.ChainName < oj.ChainName
 	})
-	return output, nil
+	sortOutcomes(res.Outcomes)
+	return res, nil
 }
 
 type csErr struct {
@@ -235,20 +387,25 @@ type csErr struct {
 	err error
 }
 
-func (fr *fetcher) run(ctx context.Context, seedCS ChainSchema) (*ChainSchema, error) {
-	goModURL := seedCS.Codebase.GitRepoURL
+// run analyses the chain described by seedCS, returning alongside it the
+// warnings about problems that didn't prevent it from being analysed.
+func (fr *fetcher) run(ctx context.Context, seedCS ChainSchema) (_ *ChainSchema, warnings []string, _ error) {
+	gitRepo := seedCS.Codebase.GitRepoURL
 
-	gu, err := url.Parse(goModURL)
+	repo, warnings, err := NormalizeRepoURL(gitRepo)
 	if err != nil {
 		logrus.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
-			"git_repo_url": goModURL,
-		}).Error("failed to URL Parse the Github repo URL from the registry")
-		return nil, err
+			"git_repo_url": gitRepo,
+		}).Error("failed to normalize the repo URL from the registry")
+		return nil, nil, &FetchError{Category: CategoryInvalidRepo, URL: gitRepo, Err: err}
 	}
-
-	// This is what rawGoModURL should look like at the very end:
-	//      https://raw.githubusercontent.com/Agoric/ag0/agoric-3.1/go.mod
-	orgRepo := strings.TrimSuffix(gu.Path, "/")
+	for _, warning := range warnings {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"chain_name": seedCS.ChainName,
+			"repo":       repo.String(),
+		}).Warn(warning)
+	}
+	seedCS.Repo = &repo
 
 	// Derive a cancellable context from the prevailing one
 	// so that an exit will end all inflight HTTP requests.
@@ -261,13 +418,11 @@ func (fr *fetcher) run(ctx context.Context, seedCS ChainSchema) (*ChainSchema, e
 	go func() {
 		defer close(frCh)
 
-		rawGoModURL := &url.URL{
-			Scheme: "https",
-			Host:   "raw.githubusercontent.com",
-			Path:   orgRepo + "/" + seedCS.Codebase.RecommendedVersion + "/go.mod",
+		url := rawGoModURL(repo, seedCS.Codebase.RecommendedVersion)
+		cs, resolved, err := fr.retrieveVersion(ctx, client, repo, seedCS.Codebase.RecommendedVersion, seedCS)
+		if cs != nil {
+			cs.Resolved = resolved
 		}
-		url := rawGoModURL.String()
-		cs, err := fr.retrieveModFile(ctx, client, url, seedCS)
 		frCh <- &csErr{
 			url: url,
 			cs:  cs,
@@ -276,43 +431,23 @@ func (fr *fetcher) run(ctx context.Context, seedCS ChainSchema) (*ChainSchema, e
 	}()
 
 	latestCh := make(chan *csErr, 1)
-	go func() (cs *ChainSchema, err error) {
-		var uri string
-
-		defer func() {
-			latestCh <- &csErr{cs: cs, err: err, url: uri}
-			close(latestCh)
-		}()
-
-		err = errors.New("skipping")
-		return
-
-		// 1. Retrieve the default branch for the repository.
-		defaultBranch, err := fr.defaultBranchForRepo(ctx, orgRepo, cs.Codebase.GitRepoURL)
-		if err != nil {
-			return nil, err
-		}
+	go func() {
+		defer close(latestCh)
 
-		// 2. Finally fetch the default branch's go.mod file.
-		latestGoModURL := &url.URL{
-			Scheme: "https",
-			Host:   "raw.githubusercontent.com",
-			Path:   orgRepo + "/" + defaultBranch + "/go.mod",
+		if fr.latest == LatestDisabled {
+			latestCh <- new(csErr)
+			return
 		}
-		uri = latestGoModURL.String()
-		return fr.retrieveModFile(ctx, client, uri, seedCS)
+		cs, uri, err := fr.retrieveLatest(ctx, client, repo, seedCS)
+		latestCh <- &csErr{cs: cs, err: err, url: uri}
 	}()
 
 	faceValueCSE := <-frCh
 	if err := faceValueCSE.err; err != nil {
 		logrus.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
-			"org_repo": orgRepo,
+			"repo": repo.String(),
 		}).Error("failed to version from the chain-registry")
-		return nil, err
-	}
-
-	if faceValueCSE.cs == nil {
-		return nil, fmt.Errorf("could not obtain the chainSchema for: %q", orgRepo)
+		return nil, nil, err
 	}
 
 	lcse := <-latestCh
@@ -321,8 +456,9 @@ func (fr *fetcher) run(ctx context.Context, seedCS ChainSchema) (*ChainSchema, e
 		//      https://github.com/AIOZNetwork/go-aioz
 		// but if we can't get the latest schema we shouldn't error.
 		logrus.WithContext(ctx).WithError(lcse.err).WithFields(logrus.Fields{
-			"org_repo": orgRepo,
+			"repo": repo.String(),
 		}).Error("failed to get the latest/live go.mod")
+		warnings = append(warnings, "latest analysis failed: "+lcse.err.Error())
 	}
 
 	// Replace the authoritative ChainSchema with
@@ -332,168 +468,98 @@ func (fr *fetcher) run(ctx context.Context, seedCS ChainSchema) (*ChainSchema, e
 	if lcse != nil && lcse.cs != nil && !reflect.DeepEqual(cs, lcse.cs) {
 		cs.Latest = lcse.cs
 	}
-	return cs, nil
-}
-
-func (fr *fetcher) retrieveChainSchema(ctx context.Context, registryDir string) (csL []*ChainSchema, rerr error) {
-	ctx, span := trace.StartSpan(ctx, "retrieveChainSchema")
-	defer span.End()
-
-	// 1. Git download the repo.
-	// Target: https://github.com/cosmos/chain-registry/archive/refs/heads/master.zip
-	bfs := os.DirFS(registryDir)
-	rerr = fs.WalkDir(bfs, ".", func(path string, d fs.DirEntry, err error) (rerr error) {
-		if err != nil {
-			return err
-		}
-		if !strings.HasSuffix(d.Name(), "chain.json") {
-			return nil
-		}
-
-		f, err := bfs.Open(path)
-		if err != nil {
-			return err
-		}
-		defer f.Close()
-
-		blob, err := io.ReadAll(f)
-		cs := new(ChainSchema)
-		if err := json.Unmarshal(blob, cs); err != nil {
-			return err
-		}
-		if cs.Codebase == nil {
-			logrus.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
-				"path": path,
-			}).Error("No codebase")
-			return nil
-		}
-		goModURL := cs.Codebase.GitRepoURL
-
-		gu, err := url.Parse(goModURL)
-		if err != nil {
-			logrus.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
-				"path":         path,
-				"git_repo_url": goModURL,
-			}).Error("failed to URL Parse the Github repo URL from the registry")
-			return nil
-		}
-
-		// This is what rawGoModURL should look like at the very end:
-		//      https://raw.githubusercontent.com/Agoric/ag0/agoric-3.1/go.mod
-		orgRepo := strings.TrimSuffix(gu.Path, "/")
-
-		// Derive a cancellable context from the prevailing one
-		// so that an exit will end all inflight HTTP requests.
-		ctx, cancel := context.WithCancel(ctx)
-		defer cancel()
-
-		client := &http.Client{Transport: fr.rt}
-
-		seedCS := *cs
-
-		type csErr struct {
-			cs  *ChainSchema
-			url string
-			err error
-		}
-		frCh := make(chan *csErr, 1)
-		go func() {
-			defer close(frCh)
-
-			rawGoModURL := &url.URL{
-				Scheme: "https",
-				Host:   "raw.githubusercontent.com",
-				Path:   orgRepo + "/" + cs.Codebase.RecommendedVersion + "/go.mod",
-			}
-			url := rawGoModURL.String()
-			cs, err := fr.retrieveModFile(ctx, client, url, seedCS)
-			frCh <- &csErr{
-				url: url,
-				cs:  cs,
-				err: err,
-			}
-		}()
-
-		latestCh := make(chan *csErr, 1)
-		go func() (cs *ChainSchema, err error) {
-			var uri string
-
-			defer func() {
-				latestCh <- &csErr{cs: cs, err: err, url: uri}
-				close(latestCh)
-			}()
-
-			// 1. Retrieve the default branch for the repository.
-			defaultBranch, err := fr.defaultBranchForRepo(ctx, orgRepo, cs.Codebase.GitRepoURL)
-			if err != nil {
-				return nil, err
-			}
-
-			// 2. Finally fetch the default branch's go.mod file.
-			latestGoModURL := &url.URL{
-				Scheme: "https",
-				Host:   "raw.githubusercontent.com",
-				Path:   orgRepo + "/" + defaultBranch + "/go.mod",
-			}
-			uri = latestGoModURL.String()
-			return fr.retrieveModFile(ctx, client, uri, seedCS)
-		}()
-
-		faceValueCSE := <-frCh
-		if err := faceValueCSE.err; err != nil {
-			logrus.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
-				"org_repo": orgRepo,
-			}).Error("failed to version from the chain-registry")
-			return nil
-		}
-		if faceValueCSE.cs == nil {
-			return nil
+	if repo := fr.cachedGitHubRepo(repo); repo != nil {
+		cs.Archived = repo.IsArchived
+		cs.LatestRelease = repo.LatestRelease
+	}
+	if fr.goSums && cs.GoModule != nil {
+		if warning := fr.retrieveGoSum(ctx, client, repo, cs); warning != "" {
+			warnings = append(warnings, warning)
 		}
+	}
+	if fr.compatibleVersions {
+		cs.Versions = fr.retrieveVersions(ctx, client, repo, cs)
+	}
+	if len(fr.releaseSources) != 0 {
+		cs.Releases = fr.retrieveReleases(ctx, client, repo, cs)
+	}
+	return cs, warnings, nil
+}
 
-		lcse := <-latestCh
-		if lcse.err != nil {
-			// Some repos don't even exist like:
-			//      https://github.com/AIOZNetwork/go-aioz
-			// but if we can't get the latest schema we shouldn't error.
-			logrus.WithContext(ctx).WithError(lcse.err).WithFields(logrus.Fields{
-				"org_repo": orgRepo,
-			}).Error("failed to get the latest/live go.mod")
-		}
+// rawGoModURL returns the URL of the go.mod file at ref, for GitHub in the form:
+//
+//	https://raw.githubusercontent.com/Agoric/ag0/agoric-3.1/go.mod
+func rawGoModURL(repo RepoID, ref string) string {
+	return repo.rawFileURL(ref, "go.mod")
+}
 
-		// Replace the authoritative ChainSchema with
-		// the version retrieved from the ChainRegistry
-		// at face value.
-		cs = faceValueCSE.cs
-		if lcse != nil && lcse.cs != nil && !reflect.DeepEqual(cs, lcse.cs) {
-			cs.Latest = lcse.cs
-		}
-		csL = append(csL, cs)
-		return nil
-	})
+// cachedGoMod returns the go.mod contents at ref if they were
+// retrieved by the batched GitHub lookups in prefetchGitHubRepos.
+func (fr *fetcher) cachedGoMod(repo RepoID, ref string) ([]byte, bool) {
+	ghRepo := fr.cachedGitHubRepo(repo)
+	if ghRepo == nil {
+		return nil, false
+	}
+	modBlob, ok := ghRepo.GoMods[ref]
+	return modBlob, ok
+}
 
-	return
+// modCacheKey identifies the go.mod file of repo at ref in the go.mod cache.
+func modCacheKey(repo RepoID, ref string) string {
+	return repo.Key() + "@" + ref
 }
 
-func (fr *fetcher) retrieveModFile(ctx context.Context, client *http.Client, url string, seed ChainSchema) (*ChainSchema, error) {
+// retrieveModFile analyses the go.mod file at url, cached as cacheKey which
+// is modCacheKey for a repository's go.mod and the URL itself otherwise.
+func (fr *fetcher) retrieveModFile(ctx context.Context, client *http.Client, cacheKey, url string, seed ChainSchema) (*ChainSchema, error) {
+	// Chains such as a mainnet and its testnets commonly share a repository,
+	// and the latest ref can be the recommended version, so go.mod files
+	// are only ever downloaded once.
+	fr.mu.Lock()
+	modBlob, ok := fr.modCache[cacheKey]
+	fr.mu.Unlock()
+	if ok {
+		return fr.parseModFile(modBlob, seed)
+	}
+
 	modReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	cs := new(ChainSchema)
-	*cs = seed
 	modRes, err := client.Do(modReq)
 	if err != nil {
-		return nil, err
+		return nil, &FetchError{Category: CategoryNetwork, URL: url, Err: err}
 	}
 	if modRes.StatusCode < 200 || modRes.StatusCode > 299 {
-		return nil, nil
+		modRes.Body.Close()
+		return nil, &FetchError{
+			Category:   CategoryHTTP,
+			URL:        url,
+			HTTPStatus: modRes.StatusCode,
+			Err:        fmt.Errorf("HTTP request failed with status: %q", modRes.Status),
+		}
 	}
-	modBlob, err := io.ReadAll(modRes.Body)
+	modBlob, err = io.ReadAll(modRes.Body)
 	modRes.Body.Close()
 	if err != nil {
-		return nil, err
+		return nil, &FetchError{Category: CategoryNetwork, URL: url, Err: err}
+	}
+
+	cs, err := fr.parseModFile(modBlob, seed)
+	if err != nil {
+		return nil, &FetchError{Category: CategoryParse, URL: url, Err: err}
 	}
+
+	fr.mu.Lock()
+	fr.modCache[cacheKey] = modBlob
+	fr.mu.Unlock()
+	return cs, nil
+}
+
+func (fr *fetcher) parseModFile(modBlob []byte, seed ChainSchema) (*ChainSchema, error) {
+	cs := new(ChainSchema)
+	*cs = seed
 	modF, err := modfile.Parse("go.mod", modBlob, nil)
 	if err != nil {
 		return nil, err
@@ -504,6 +570,9 @@ func (fr *fetcher) retrieveModFile(ctx context.Context, client *http.Client, url
 	cs.IBCVersion = ibcVers
 	cs.TendermintVersion = tendermintVers
 	cs.CosmosSDKVersion = cosmosSDKVers
+	if fr.goModules {
+		cs.GoModule = newGoModule(modF)
+	}
 
 	// Table columns:
 	// Chain,Git_Repo,Contact,Account_Manager,Is_mainnet,Mainnet GH release, CosmosSDK,Tendermint, IBC
@@ -519,90 +588,31 @@ func (fr *fetcher) retrieveModFile(ctx context.Context, client *http.Client, url
 	return cs, nil
 }
 
-func (fr *fetcher) defaultBranchForRepo(ctx context.Context, orgRepo, repoURL string) (string, error) {
+func (fr *fetcher) defaultBranchForRepo(ctx context.Context, repo RepoID) (string, error) {
 	// 1. A problem we encounter is that we run into API quota limits
 	// when we invoke the https://api.github.com/repos/{org}/{repo}/ link
-	// thus:
-	// * Firstly try and see if the go.mod file exists on commonly
-	// 2. As the last resort, actually fetch from the Github repo API.
-	// In order to bypass Github API quota limits, we have to become inventive and instead
-	// use a shallow git clone eliminating blobs of a big size so that the operation downloads
-	// only a few kilobytes:
-	//
-	//	git clone --no-checkout --filter=blob:60 <URL>
-	tmpDirName := strings.ReplaceAll(orgRepo, string(os.PathSeparator), "-")
-	tmpDir, err := os.MkdirTemp(os.TempDir(), tmpDirName)
-	if err != nil {
-		return "", err
-	}
-	defer os.RemoveAll(tmpDir)
-
-	cmd := exec.CommandContext(ctx,
-		"git", "clone", "--no-checkout", "--filter=blob:limit=40", repoURL, tmpDir,
-	)
-	if _, err := cmd.CombinedOutput(); err != nil {
-		return "", err
-	}
-
-	// Now just read the .git/HEAD file.
-	gitHEAD, err := os.ReadFile(filepath.Join(tmpDir, ".git", "HEAD"))
-	if err != nil {
-		return "", err
-	}
-	// Expecting the form:
-	//    ref: refs/heads/Agoric
-	splits := strings.Split(string(gitHEAD), ":")
-	if len(splits) != 2 {
-		return "", fmt.Errorf("could not split the .git/HEAD file, got: %s", gitHEAD)
-	}
-	i := strings.LastIndex(splits[1], "/")
-	refsOfHead := strings.TrimSpace(splits[1][i+1:])
-	return refsOfHead, nil
-}
-
-func (fr *fetcher) githubFetchDefaultBranchForRepo(ctx context.Context, client *http.Client, orgRepo string) (string, error) {
-	// 1. Firstly check if the repository was cached or not.
-	fr.mu.Lock()
-	repo, ok := fr.repoCache[orgRepo]
-	fr.mu.Unlock()
-
-	if ok && repo != nil {
-		return repo.GetDefaultBranch(), nil
+	// thus only ask the API if we've got a GitHub token.
+	if fr.githubToken != "" && repo.IsGitHub() {
+		client := &http.Client{Transport: fr.rt}
+		if branch, err := fr.githubFetchDefaultBranchForRepo(ctx, client, repo); err == nil {
+			return branch, nil
+		}
 	}
 
-	apiURL := "https://api.github.com/repos" + orgRepo
-	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("Accept", "application/vnd.github+json")
-	res, err := client.Do(req)
+	// 2. Otherwise ask the git server directly which branch HEAD points to,
+	// just like `git ls-remote --symref <URL> HEAD` does. The advertisement
+	// only costs a few kilobytes and isn't subject to the API quota limits.
+	refs, err := fr.lsRemote(ctx, repo)
 	if err != nil {
 		return "", err
 	}
-	blob, err := io.ReadAll(res.Body)
-	res.Body.Close()
-	if res.StatusCode < 200 || res.StatusCode > 299 {
-		errStr := res.Status
-		if len(blob) != 0 {
-			errStr = string(blob)
-		}
-		return "", errors.New(errStr)
-	}
-
-	repo = new(github.Repository)
-	if err := json.Unmarshal(blob, repo); err != nil {
-		return "", err
+	if refs.Head == "" {
+		return "", fmt.Errorf("%q did not advertise the target of HEAD", repo.URL())
 	}
-
-	fr.mu.Lock()
-	defer fr.mu.Unlock()
-	fr.repoCache[orgRepo] = repo
-
-	return repo.GetDefaultBranch(), nil
+	return refs.DefaultBranch(), nil
 }
 
-var reTargets = regexp.MustCompile("cosmos-sdk|tendermint/tendermint|/ibc")
+var reTargets = regexp.MustCompile("cosmos-sdk|tendermint/tendermint|cometbft|/ibc")
 
 func (fr *fetcher) downloadAndUnzipRegistry(ctx context.Context, registryDir string) (rerr error) {
 	ctx, span := trace.StartSpan(ctx, "downloadAndUnzipRegistry")
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"golang.org/x/mod/modfile"
 )
 
 var testdataZip, testdataGoMod, testdataGithubRepo, testdataLatestGoMod []byte
@@ -58,6 +59,17 @@ func TestFetchChainData(t *testing.T) {
 			rw.Write(testdataGithubRepo)
 			return
 		}
+		if strings.HasSuffix(req.URL.Path, "/info/refs") {
+			rw.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
+			rw.Write([]byte(pktLines(
+				"# service=git-upload-pack\n",
+				"",
+				oidMain+" HEAD\x00symref=HEAD:refs/heads/main\n",
+				oidMain+" refs/heads/main\n",
+				"",
+			)))
+			return
+		}
 
 		if strings.Contains(req.URL.Path, "Agoric/ag0/main/go.mod") {
 			rw.Write(testdataLatestGoMod)
@@ -78,7 +90,7 @@ func TestFetchChainData(t *testing.T) {
 	}
 
 	art := &alwaysToURLRoundTripper{next: cst.Client(), destURL: destURL}
-	fetcher := newFetcher(art)
+	fetcher := newFetcher(art, WithLatest(LatestDefaultBranch))
 
 	ctx := context.Background()
 	got, err := fetcher.fetchChainData(ctx)
@@ -98,6 +110,7 @@ func TestFetchChainData(t *testing.T) {
 				RecommendedVersion: "agoric-3.1",
 				CompatibleVersions: []string{"agoric-3.1"},
 			},
+			Repo:              &RepoID{Host: "github.com", Owner: "Agoric", Repo: "ag0"},
 			IsMainnet:         "yes",
 			TendermintVersion: "v0.34.13@github.com/tendermint/tendermint",
 			CosmosSDKVersion:  "v0.44.2-alpha.agoric.gaiad.1@github.com/agoric-labs/cosmos-sdk",
@@ -113,6 +126,7 @@ func TestFetchChainData(t *testing.T) {
 					RecommendedVersion: "agoric-3.1",
 					CompatibleVersions: []string{"agoric-3.1"},
 				},
+				Repo:              &RepoID{Host: "github.com", Owner: "Agoric", Repo: "ag0"},
 				IsMainnet:         "yes",
 				TendermintVersion: "v0.37.13@github.com/tendermint/tendermint",
 				CosmosSDKVersion:  "v0.44.2-alpha.agoric.gaiad.1@github.com/agoric-labs/cosmos-sdk",
@@ -130,6 +144,7 @@ func TestFetchChainData(t *testing.T) {
 				RecommendedVersion: "v1.2.0",
 				CompatibleVersions: []string{"v1.2.0"},
 			},
+			Repo:              &RepoID{Host: "github.com", Owner: "AIOZNetwork", Repo: "go-aioz"},
 			IsMainnet:         "yes",
 			TendermintVersion: "v0.34.13@github.com/tendermint/tendermint",
 			CosmosSDKVersion:  "v0.44.2-alpha.agoric.gaiad.1@github.com/agoric-labs/cosmos-sdk",
@@ -146,6 +161,7 @@ func TestFetchChainData(t *testing.T) {
 				RecommendedVersion: "v0.16.3",
 				CompatibleVersions: []string{"v0.16.3"},
 			},
+			Repo:              &RepoID{Host: "github.com", Owner: "ovrclk", Repo: "akash"},
 			IsMainnet:         "yes",
 			TendermintVersion: "v0.34.13@github.com/tendermint/tendermint",
 			CosmosSDKVersion:  "v0.44.2-alpha.agoric.gaiad.1@github.com/agoric-labs/cosmos-sdk",
@@ -169,6 +185,7 @@ func TestFetchChainData(t *testing.T) {
 				RecommendedVersion: "chaosnet-multichain",
 				CompatibleVersions: []string{"chaosnet-multichain"},
 			},
+			Repo:              &RepoID{Host: "gitlab.com", Owner: "thorchain", Repo: "thornode"},
 			IsMainnet:         "yes",
 			TendermintVersion: "v0.34.13@github.com/tendermint/tendermint",
 			CosmosSDKVersion:  "v0.44.2-alpha.agoric.gaiad.1@github.com/agoric-labs/cosmos-sdk",
@@ -185,6 +202,7 @@ func TestFetchChainData(t *testing.T) {
 				RecommendedVersion: "v1.0.3",
 				CompatibleVersions: []string{"v1.0.3"},
 			},
+			Repo:              &RepoID{Host: "github.com", Owner: "umee-network", Repo: "umee"},
 			IsMainnet:         "yes",
 			TendermintVersion: "v0.34.13@github.com/tendermint/tendermint",
 			CosmosSDKVersion:  "v0.44.2-alpha.agoric.gaiad.1@github.com/agoric-labs/cosmos-sdk",
@@ -201,6 +219,7 @@ func TestFetchChainData(t *testing.T) {
 				RecommendedVersion: "v1.0.0",
 				CompatibleVersions: []string{"v1.0.0"},
 			},
+			Repo:              &RepoID{Host: "github.com", Owner: "vidulum", Repo: "mainnet"},
 			IsMainnet:         "yes",
 			TendermintVersion: "v0.34.13@github.com/tendermint/tendermint",
 			CosmosSDKVersion:  "v0.44.2-alpha.agoric.gaiad.1@github.com/agoric-labs/cosmos-sdk",
@@ -214,9 +233,24 @@ func TestFetchChainData(t *testing.T) {
 }
 
 func TestDefaultBranchForRepo(t *testing.T) {
+	cst := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/Agoric/ag0/info/refs" || req.URL.Query().Get("service") != "git-upload-pack" {
+			http.NotFound(rw, req)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
+		rw.Write([]byte(agoricAdvertisement))
+	}))
+	defer cst.Close()
+
+	destURL, err := url.Parse(cst.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	ctx := context.Background()
-	fr := newFetcher(nil)
-	head, err := fr.defaultBranchForRepo(ctx, "Agoric/ag0", "https://github.com/Agoric/ag0")
+	fr := newFetcher(&alwaysToURLRoundTripper{next: cst.Client(), destURL: destURL})
+	head, err := fr.defaultBranchForRepo(ctx, RepoID{Host: "github.com", Owner: "Agoric", Repo: "ag0"})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -224,3 +258,49 @@ func TestDefaultBranchForRepo(t *testing.T) {
 		t.Fatalf("Default branch mismatch:\n\tGot:  %q\n\tWant: %q", g, w)
 	}
 }
+
+func TestExtractCosmosTuples(t *testing.T) {
+	tests := []struct {
+		name, goMod              string
+		wantSDK, wantTM, wantIBC string
+	}{
+		{
+			name: "cometbft require",
+			goMod: `module example.com/chain
+require (
+	github.com/cometbft/cometbft v0.38.2
+	github.com/cometbft/cometbft-db v0.8.0
+	github.com/cosmos/cosmos-sdk v0.50.1
+	github.com/cosmos/ibc-go/v8 v8.0.0
+)`,
+			wantSDK: "v0.50.1", wantTM: "v0.38.2", wantIBC: "v8.0.0",
+		},
+		{
+			name: "tendermint replaced with cometbft",
+			goMod: `module example.com/chain
+require (
+	github.com/cosmos/cosmos-sdk v0.45.16
+	github.com/tendermint/tendermint v0.34.27
+)
+replace github.com/tendermint/tendermint => github.com/cometbft/cometbft v0.34.29`,
+			wantSDK: "v0.45.16", wantTM: "v0.34.29@github.com/cometbft/cometbft",
+		},
+		{
+			name: "cometbft fork",
+			goMod: `module example.com/chain
+require github.com/cometbft/cometbft v0.37.2
+replace github.com/cometbft/cometbft => github.com/skip-mev/cometbft v0.37.2-skip.1`,
+			wantTM: "v0.37.2-skip.1@github.com/skip-mev/cometbft",
+		},
+	}
+	for _, tt := range tests {
+		modF, err := modfile.Parse("go.mod", []byte(tt.goMod), nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		sdk, tm, ibc := extractCosmosTuples(modF)
+		if sdk != tt.wantSDK || tm != tt.wantTM || ibc != tt.wantIBC {
+			t.Errorf("%s: got (%q, %q, %q), want (%q, %q, %q)", tt.name, sdk, tm, ibc, tt.wantSDK, tt.wantTM, tt.wantIBC)
+		}
+	}
+}
@@ -2,30 +2,303 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/cosmos/chainparse"
+	_ "modernc.org/sqlite"
 )
 
 func main() {
+	githubTokenFile := flag.String("github-token-file", "", "Path to a file containing a GitHub token, overriding the "+chainparse.GitHubTokenEnv+" and "+chainparse.GitHubTokenFileEnv+" environment variables")
+	latestName := flag.String("latest", "none", fmt.Sprintf("The strategy for the latest analysis, one of %q or none to disable it", chainparse.LatestStrategies))
+	latestConcurrency := flag.Int("latest-concurrency", chainparse.DefaultLatestConcurrency, "The maximum number of chains undergoing the latest analysis at once")
+	compatibleVersions := flag.Bool("compatible-versions", false, "Analyse every one of the registry's compatible_versions and not just recommended_version")
+	releaseSourceNames := flag.String("releases", "", fmt.Sprintf("A comma separated list of sources to discover each chain's releases from, any of %q", chainparse.ReleaseSources))
+	recordPath := flag.String("record", "", "Record every HTTP interaction of the run into this cassette file")
+	replayPath := flag.String("replay", "", "Replay the HTTP interactions recorded in this cassette file instead of using the network")
+	format := flag.String("format", "csv", "The output format: csv, tsv, json for the chains and outcomes as served by /report, ndjson to stream a chain per line as soon as it's analysed, a markdown or html report, an xlsx workbook, or the fork lineage as a dot or mermaid graph")
+	columnsSpec := flag.String("columns", "", "A comma separated list of the csv or tsv columns to output, each a field path such as codebase.git_repo or latest.cosmos_sdk_version optionally followed by =Header to rename it (default the spreadsheet's columns)")
+	templatePath := flag.String("template", "", "Render the results through this Go template file instead of -format, with html/template if it's named *.html or *.html.tmpl and text/template otherwise")
+	sqlitePath := flag.String("sqlite", "", "Also append the run to the SQLite database at this path, creating it if necessary")
+	sheetsCredentials := flag.String("sheets-credentials", "", "Path to the JSON key file of a Google service account to write the results into the -sheets-id spreadsheet with")
+	sheetsID := flag.String("sheets-id", "", "The ID of the Google spreadsheet to bring up to date with the results, as found in its URL")
+	feedPath := flag.String("feed", "", "Write an Atom feed of the version changes since the previous runs to this file, which requires -feed-state")
+	feedStatePath := flag.String("feed-state", "", "The file remembering the versions of the previous run and the changes so far, created if necessary and updated by every run")
+	feedChains := flag.String("feed-chains", "", "A comma separated list of the chains to include in the -feed (default all)")
+	feedModules := flag.String("feed-modules", "", fmt.Sprintf("A comma separated list of the modules to include in the -feed, any of %q (default all)", chainparse.FeedModules))
+	sbomDir := flag.String("sbom", "", "Write a CycloneDX (chain_name.cdx.json) and an SPDX (chain_name.spdx.json) SBOM of every chain into this directory, retrieving go.sum files for their h1 hashes")
+	flag.Parse()
+
+	var comma rune
+	switch *format {
+	case "csv":
+		comma = ','
+	case "tsv":
+		comma = '\t'
+	case "json", "ndjson", "markdown", "html", "xlsx", "dot", "mermaid":
+	default:
+		panic(fmt.Sprintf("unknown format %q, expected csv, tsv, json, ndjson, markdown, html, xlsx, dot or mermaid", *format))
+	}
+	var tmpl *chainparse.Template
+	if *templatePath != "" {
+		var err error
+		if tmpl, err = chainparse.LoadTemplate(*templatePath); err != nil {
+			panic(err)
+		}
+	}
+	var sheets *chainparse.SheetsWriter
+	if (*sheetsCredentials == "") != (*sheetsID == "") {
+		panic("-sheets-credentials and -sheets-id go together")
+	}
+	if *sheetsID != "" {
+		account, err := chainparse.LoadServiceAccount(*sheetsCredentials)
+		if err != nil {
+			panic(err)
+		}
+		sheets = chainparse.NewSheetsWriter(nil, account, *sheetsID)
+	}
+	if *feedPath != "" && *feedStatePath == "" {
+		panic("-feed requires -feed-state to compare against the previous run")
+	}
+	feedFilter, err := chainparse.ParseFeedFilter(*feedChains, *feedModules)
+	if err != nil {
+		panic(err)
+	}
+	columns := chainparse.DefaultColumns
+	if *columnsSpec != "" {
+		var err error
+		if columns, err = chainparse.ParseColumns(*columnsSpec); err != nil {
+			panic(err)
+		}
+	}
+
+	githubToken, err := chainparse.LoadGitHubToken(*githubTokenFile)
+	if err != nil {
+		panic(err)
+	}
+	latest, err := chainparse.ParseLatestStrategy(*latestName)
+	if err != nil {
+		panic(err)
+	}
+	releaseSources, err := chainparse.ParseReleaseSources(*releaseSourceNames)
+	if err != nil {
+		panic(err)
+	}
+	opts := []chainparse.Option{
+		chainparse.WithGitHubToken(githubToken),
+		chainparse.WithLatest(latest),
+		chainparse.WithLatestConcurrency(*latestConcurrency),
+	}
+	if *compatibleVersions {
+		opts = append(opts, chainparse.WithCompatibleVersions())
+	}
+	if len(releaseSources) != 0 {
+		opts = append(opts, chainparse.WithReleaseDiscovery(releaseSources...))
+	}
+	if *sbomDir != "" {
+		opts = append(opts, chainparse.WithGoSums())
+	}
+	if *sqlitePath != "" || *format == "dot" || *format == "mermaid" {
+		// The replace_directives table and the upstream versions of
+		// forks are populated from the go.mod files.
+		opts = append(opts, chainparse.WithGoModules())
+	}
+
+	var streamErr error
+	if *format == "ndjson" && tmpl == nil {
+		enc := json.NewEncoder(os.Stdout)
+		opts = append(opts, chainparse.WithProgress(func(cs *chainparse.ChainSchema, _ *chainparse.Outcome) {
+			if cs != nil && streamErr == nil {
+				streamErr = enc.Encode(cs)
+			}
+		}))
+	}
+
+	var rt http.RoundTripper
+	var rec *chainparse.Recorder
+	switch {
+	case *recordPath != "" && *replayPath != "":
+		panic("-record and -replay are mutually exclusive")
+	case *recordPath != "":
+		rec = chainparse.NewRecorder(nil)
+		rt = rec
+	case *replayPath != "":
+		cassette, err := chainparse.LoadCassette(*replayPath)
+		if err != nil {
+			panic(err)
+		}
+		rt = chainparse.NewReplayer(cassette)
+	}
+
 	ctx := context.Background()
-	csL, err := chainparse.RetrieveChainData(ctx, nil)
+	startedAt := time.Now()
+	res, err := chainparse.RetrieveResult(ctx, rt, opts...)
+	if rec != nil {
+		// Save the cassette even if the run failed, that's when it's most useful.
+		if serr := rec.Save(*recordPath); serr != nil {
+			panic(serr)
+		}
+	}
 	if err != nil {
 		panic(err)
 	}
 
-	printHeader()
+	switch {
+	case tmpl != nil:
+		err = tmpl.Execute(os.Stdout, res, time.Now())
+	case *format == "ndjson":
+		err = streamErr
+	case *format == "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(res)
+	case *format == "markdown":
+		err = chainparse.WriteMarkdownReport(os.Stdout, res, time.Now())
+	case *format == "html":
+		err = chainparse.WriteHTMLReport(os.Stdout, res, time.Now())
+	case *format == "xlsx":
+		err = chainparse.WriteXLSX(os.Stdout, res)
+	case *format == "dot":
+		err = chainparse.WriteDOT(os.Stdout, chainparse.NewLineage(res))
+	case *format == "mermaid":
+		err = chainparse.WriteMermaid(os.Stdout, chainparse.NewLineage(res))
+	default:
+		err = chainparse.WriteTable(os.Stdout, comma, columns, res.Chains)
+	}
+	if err != nil {
+		panic(err)
+	}
 
-	for _, cs := range csL {
-		line := []string{
-			cs.PrettyName, cs.Codebase.GitRepoURL, cs.Contact, cs.AccountManager, cs.IsMainnet,
-			cs.Codebase.RecommendedVersion, cs.CosmosSDKVersion, cs.TendermintVersion, cs.IBCVersion,
+	if *sqlitePath != "" {
+		if err := writeSQLite(ctx, *sqlitePath, res, startedAt); err != nil {
+			panic(err)
 		}
-		fmt.Println(strings.Join(line, ","))
 	}
+
+	if *sbomDir != "" {
+		if err := writeSBOMs(*sbomDir, res, startedAt); err != nil {
+			panic(err)
+		}
+	}
+
+	if *feedStatePath != "" {
+		if err := writeFeed(*feedPath, *feedStatePath, feedFilter, res); err != nil {
+			panic(err)
+		}
+	}
+
+	if sheets != nil {
+		su, err := sheets.Write(ctx, res)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Fprintf(os.Stderr, "chainparse: updated %d cells of %d rows and appended %d rows to the spreadsheet\n",
+			su.UpdatedCells, su.UpdatedRows, su.AppendedRows)
+	}
+
+	printSummary(res)
 }
 
-func printHeader() {
-	fmt.Println("Chain,Git_Repo,Contact,Account_Manager,Is_mainnet,Mainnet GH release,CosmosSDK,Tendermint,IBC")
+func writeSQLite(ctx context.Context, path string, res *chainparse.Result, startedAt time.Time) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	runID, err := chainparse.WriteSQLite(ctx, db, res, startedAt)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "chainparse: wrote run %d to %s\n", runID, path)
+	return db.Close()
+}
+
+func writeSBOMs(dir string, res *chainparse.Result, generatedAt time.Time) error {
+	// chain_name comes from the registry, so make sure that it can't write
+	// outside of dir nor have two chains overwrite each other's SBOMs.
+	seen := make(map[string]bool, len(res.Chains))
+	for _, cs := range res.Chains {
+		name := cs.ChainName
+		if name == "" || name == "." || name == ".." || filepath.Base(name) != name || strings.ContainsAny(name, `/\`) {
+			return fmt.Errorf("chain_name %q can't be used as a file name", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("several chains are named %q", name)
+		}
+		seen[name] = true
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, cs := range res.Chains {
+		for ext, write := range map[string]func(io.Writer, *chainparse.ChainSchema, time.Time) error{
+			".cdx.json":  chainparse.WriteCycloneDX,
+			".spdx.json": chainparse.WriteSPDX,
+		} {
+			f, err := os.Create(filepath.Join(dir, cs.ChainName+ext))
+			if err != nil {
+				return err
+			}
+			if err := write(f, cs, generatedAt); err != nil {
+				f.Close()
+				return fmt.Errorf("%s: %w", cs.ChainName, err)
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeFeed(path, statePath string, filter chainparse.FeedFilter, res *chainparse.Result) error {
+	cl, err := chainparse.LoadChangeLog(statePath)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	cl.Record(res, now)
+	if err := cl.Save(statePath); err != nil {
+		return err
+	}
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := chainparse.WriteAtomFeed(f, cl.Changes, filter, "", now); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// printSummary reports the outcomes to stderr so that they don't end up in the output.
+func printSummary(res *chainparse.Result) {
+	fmt.Fprintf(os.Stderr, "chainparse: %s\n", res.Summary())
+	for _, out := range res.Failures() {
+		detail := string(out.Category)
+		if out.HTTPStatus != 0 {
+			detail += fmt.Sprintf(", HTTP %d", out.HTTPStatus)
+		}
+		fmt.Fprintf(os.Stderr, "  failed %s (%s): %s", out.ChainName, detail, out.Message)
+		if out.URL != "" {
+			fmt.Fprintf(os.Stderr, " [%s]", out.URL)
+		}
+		fmt.Fprintln(os.Stderr)
+	}
 }
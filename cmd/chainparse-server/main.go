@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	_ "embed"
 	"flag"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"contrib.go.opencensus.io/exporter/ocagent"
 	"github.com/sirupsen/logrus"
@@ -20,8 +23,58 @@ func main() {
 	ocAgentAddress := flag.String("ocagent-addr", "", "The address to connect to the OCAgent")
 
 	addr := flag.String("addr", ":8834", "The address to serve traffic on")
+	githubTokenFile := flag.String("github-token-file", "", "Path to a file containing a GitHub token, overriding the "+chainparse.GitHubTokenEnv+" and "+chainparse.GitHubTokenFileEnv+" environment variables")
+	latestName := flag.String("latest", "none", fmt.Sprintf("The strategy for the latest analysis, one of %q or none to disable it", chainparse.LatestStrategies))
+	latestConcurrency := flag.Int("latest-concurrency", chainparse.DefaultLatestConcurrency, "The maximum number of chains undergoing the latest analysis at once")
+	compatibleVersions := flag.Bool("compatible-versions", false, "Analyse every one of the registry's compatible_versions and not just recommended_version")
+	releaseSourceNames := flag.String("releases", "", fmt.Sprintf("A comma separated list of sources to discover each chain's releases from, any of %q", chainparse.ReleaseSources))
+	recordPath := flag.String("record", "", "Record the HTTP interactions of the last refresh into this cassette file, which is rewritten after each refresh")
+	replayPath := flag.String("replay", "", "Replay the HTTP interactions recorded in this cassette file instead of using the network")
+	templatePaths := flag.String("templates", "", "A comma separated list of Go template files to serve at /report?template=name, name being the file name without its extensions")
+	feedStatePath := flag.String("feed-state", "", "The file remembering the versions of the last refresh and the changes so far for /feed.atom, created if necessary and updated by every refresh")
+	refreshInterval := flag.Duration("refresh", chainparse.DefaultRefreshInterval, "How often to refresh the snapshot of the chains that is served, in the background")
 	flag.Parse()
 
+	githubToken, err := chainparse.LoadGitHubToken(*githubTokenFile)
+	if err != nil {
+		panic(err)
+	}
+	latest, err := chainparse.ParseLatestStrategy(*latestName)
+	if err != nil {
+		panic(err)
+	}
+	releaseSources, err := chainparse.ParseReleaseSources(*releaseSourceNames)
+	if err != nil {
+		panic(err)
+	}
+	opts := []chainparse.Option{
+		chainparse.WithGitHubToken(githubToken),
+		chainparse.WithLatest(latest),
+		chainparse.WithLatestConcurrency(*latestConcurrency),
+	}
+	if *compatibleVersions {
+		opts = append(opts, chainparse.WithCompatibleVersions())
+	}
+	if len(releaseSources) != 0 {
+		opts = append(opts, chainparse.WithReleaseDiscovery(releaseSources...))
+	}
+	if *feedStatePath != "" {
+		cl, err := chainparse.LoadChangeLog(*feedStatePath)
+		if err != nil {
+			panic(err)
+		}
+		opts = append(opts, chainparse.WithChangeLog(cl, *feedStatePath))
+	}
+	if *templatePaths != "" {
+		for _, path := range strings.Split(*templatePaths, ",") {
+			tmpl, err := chainparse.LoadTemplate(strings.TrimSpace(path))
+			if err != nil {
+				panic(err)
+			}
+			opts = append(opts, chainparse.WithTemplates(tmpl))
+		}
+	}
+
 	oce, err := ocagent.NewExporter(
 		ocagent.WithInsecure(),
 		ocagent.WithServiceName("cmd/chainparse"),
@@ -35,9 +88,44 @@ func main() {
 		panic("mockDataJSON is empty!")
 	}
 
+	var rt http.RoundTripper = new(ochttp.Transport)
+	var rec *chainparse.Recorder
+	switch {
+	case *recordPath != "" && *replayPath != "":
+		panic("-record and -replay are mutually exclusive")
+	case *recordPath != "":
+		rec = chainparse.NewRecorder(rt)
+		rt = rec
+	case *replayPath != "":
+		cassette, err := chainparse.LoadCassette(*replayPath)
+		if err != nil {
+			panic(err)
+		}
+		rt = chainparse.NewReplayer(cassette)
+	}
+
 	mux := http.NewServeMux()
-	cp := chainparse.NewChainParser(new(ochttp.Transport))
-	mux.HandleFunc("/", http.HandlerFunc(cp.FetchData))
+	cp := chainparse.NewChainParser(rt, opts...)
+	var onRefresh func(error)
+	if rec != nil {
+		// Save the cassette of every refresh, so that it survives the server
+		// being stopped, and start afresh for the next one so that the
+		// cassette doesn't grow with every refresh.
+		onRefresh = func(error) {
+			if err := rec.Save(*recordPath); err != nil {
+				logrus.WithError(err).Error("failed to save the cassette")
+			}
+			rec.Reset()
+		}
+	}
+	go cp.Refresh(context.Background(), *refreshInterval, onRefresh)
+	mux.HandleFunc("/", cp.FetchData)
+	mux.HandleFunc("/report", cp.FetchReport)
+	mux.HandleFunc("/v1/chains", cp.Chains)
+	mux.HandleFunc("/v1/chains/", cp.Chains)
+	mux.HandleFunc("/metrics", cp.Metrics)
+	mux.HandleFunc("/feed.atom", cp.Feed)
+	mux.HandleFunc("/badge/", cp.Badge)
 	mux.HandleFunc("/mock", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
 		rw.Write(mockDataJSON)
 	}))
@@ -0,0 +1,208 @@
+package chainparse
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Column selects a field of ChainSchema for tabular output.
+type Column struct {
+	// Path is the dotted path of JSON field names leading to the field,
+	// such as "codebase.git_repo" or "latest.cosmos_sdk_version". Elements
+	// of lists are selected by index, e.g. "versions.0.version".
+	Path string
+
+	// Header is the column's title, the path itself if empty.
+	Header string
+}
+
+// Title returns the column's header.
+func (col Column) Title() string {
+	if col.Header != "" {
+		return col.Header
+	}
+	return col.Path
+}
+
+// DefaultColumns are the columns of the chainparse spreadsheet.
+var DefaultColumns = []Column{
+	{Path: "pretty_name", Header: "Chain"},
+	{Path: "codebase.git_repo", Header: "Git_Repo"},
+	{Path: "contact", Header: "Contact"},
+	{Path: "account_manager", Header: "Account_Manager"},
+	{Path: "is_mainnet", Header: "Is_mainnet"},
+	{Path: "codebase.recommended_version", Header: "Mainnet GH release"},
+	{Path: "cosmos_sdk_version", Header: "CosmosSDK"},
+	{Path: "tendermint_version", Header: "Tendermint"},
+	{Path: "ibc_version", Header: "IBC"},
+}
+
+// ParseColumns parses a comma separated list of columns, each either a
+// path or a path followed by "=" and the header to use for it, such as
+// "chain_name=Chain,latest.cosmos_sdk_version=Latest CosmosSDK".
+// Unknown paths are rejected.
+func ParseColumns(spec string) ([]Column, error) {
+	var cols []Column
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		col := Column{Path: item}
+		if i := strings.IndexByte(item, '='); i >= 0 {
+			col.Path, col.Header = strings.TrimSpace(item[:i]), strings.TrimSpace(item[i+1:])
+		}
+		if err := checkColumnPath(col.Path); err != nil {
+			return nil, err
+		}
+		cols = append(cols, col)
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("no columns in %q", spec)
+	}
+	return cols, nil
+}
+
+var chainSchemaType = reflect.TypeOf(ChainSchema{})
+
+// jsonFieldIndex returns the index of the field of struct type t named name in JSON.
+func jsonFieldIndex(t reflect.Type, name string) (int, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("json"), ",")[0]
+		if tag == "-" || !f.IsExported() {
+			continue
+		}
+		if tag == "" {
+			tag = f.Name
+		}
+		if tag == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func checkColumnPath(path string) error {
+	t := chainSchemaType
+	for _, seg := range strings.Split(path, ".") {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			i, ok := jsonFieldIndex(t, seg)
+			if !ok {
+				return fmt.Errorf("unknown column %q: %s has no field %q", path, t.Name(), seg)
+			}
+			t = t.Field(i).Type
+		case reflect.Slice:
+			if _, err := strconv.Atoi(seg); err != nil {
+				return fmt.Errorf("unknown column %q: %q isn't a list index", path, seg)
+			}
+			t = t.Elem()
+		case reflect.Map:
+			t = t.Elem()
+		default:
+			return fmt.Errorf("unknown column %q: %q has no fields", path, seg)
+		}
+	}
+	return nil
+}
+
+// Value returns the column's value for cs, or "" if any
+// field along its path is unset, such as a nil Codebase.
+func (col Column) Value(cs *ChainSchema) string {
+	v := reflect.ValueOf(cs)
+	for _, seg := range strings.Split(col.Path, ".") {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return ""
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Struct:
+			i, ok := jsonFieldIndex(v.Type(), seg)
+			if !ok {
+				return ""
+			}
+			v = v.Field(i)
+		case reflect.Slice:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= v.Len() {
+				return ""
+			}
+			v = v.Index(i)
+		case reflect.Map:
+			v = v.MapIndex(reflect.ValueOf(seg).Convert(v.Type().Key()))
+			if !v.IsValid() {
+				return ""
+			}
+		default:
+			return ""
+		}
+	}
+	return formatColumnValue(v)
+}
+
+func formatColumnValue(v reflect.Value) string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.String {
+			// Lists of versions read best as a plain list.
+			items := make([]string, v.Len())
+			for i := range items {
+				items[i] = v.Index(i).String()
+			}
+			return strings.Join(items, ",")
+		}
+	}
+	blob, err := json.Marshal(v.Interface())
+	if err != nil {
+		return ""
+	}
+	return string(blob)
+}
+
+// WriteTable writes a header row then a row per chain with the values of cols,
+// quoted per RFC 4180. comma separates the values, ',' for CSV or '\t' for TSV.
+func WriteTable(w io.Writer, comma rune, cols []Column, chains []*ChainSchema) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+
+	row := make([]string, len(cols))
+	for i, col := range cols {
+		row[i] = col.Title()
+	}
+	if err := cw.Write(row); err != nil {
+		return err
+	}
+	for _, cs := range chains {
+		for i, col := range cols {
+			row[i] = col.Value(cs)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,76 @@
+package chainparse
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseColumns(t *testing.T) {
+	got, err := ParseColumns("chain_name=Chain, codebase.git_repo ,latest.cosmos_sdk_version=Latest SDK,versions.1.version,releases.errors.proxy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Column{
+		{Path: "chain_name", Header: "Chain"},
+		{Path: "codebase.git_repo"},
+		{Path: "latest.cosmos_sdk_version", Header: "Latest SDK"},
+		{Path: "versions.1.version"},
+		{Path: "releases.errors.proxy"},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("Columns mismatch: got - want +\n%s", diff)
+	}
+
+	for _, spec := range []string{"", "nope", "codebase.nope", "chain_name.nope", "versions.first.version"} {
+		if cols, err := ParseColumns(spec); err == nil {
+			t.Errorf("%q: expected an error, got %#v", spec, cols)
+		}
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	chains := []*ChainSchema{
+		{
+			ChainName:  "agoric",
+			PrettyName: `Agoric, "the" chain`,
+			Codebase: &Codebase{
+				GitRepoURL:         "https://github.com/Agoric/ag0",
+				CompatibleVersions: []string{"agoric-3.1", "agoric-3.0"},
+			},
+			Archived: true,
+			Versions: []*VersionInfo{{Version: "agoric-3.1"}, {Version: "agoric-3.0", IBCVersion: "v1.2.0"}},
+			Latest:   &ChainSchema{CosmosSDKVersion: "v0.46.0"},
+		},
+		// Neither a codebase nor the latest analysis must not trip anything up.
+		{ChainName: "bitcoin", PrettyName: "Bitcoin"},
+	}
+	cols, err := ParseColumns("pretty_name=Chain,codebase.git_repo=Repo,codebase.compatible_versions,archived,versions.1.ibc_version,latest.cosmos_sdk_version=Latest SDK")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var csvOut strings.Builder
+	if err := WriteTable(&csvOut, ',', cols, chains); err != nil {
+		t.Fatal(err)
+	}
+	wantCSV := `Chain,Repo,codebase.compatible_versions,archived,versions.1.ibc_version,Latest SDK
+"Agoric, ""the"" chain",https://github.com/Agoric/ag0,"agoric-3.1,agoric-3.0",true,v1.2.0,v0.46.0
+Bitcoin,,,false,,
+`
+	if diff := cmp.Diff(csvOut.String(), wantCSV); diff != "" {
+		t.Errorf("CSV mismatch: got - want +\n%s", diff)
+	}
+
+	var tsvOut strings.Builder
+	if err := WriteTable(&tsvOut, '\t', DefaultColumns[:2], chains); err != nil {
+		t.Fatal(err)
+	}
+	wantTSV := "Chain\tGit_Repo\n" +
+		"\"Agoric, \"\"the\"\" chain\"\thttps://github.com/Agoric/ag0\n" +
+		"Bitcoin\t\n"
+	if diff := cmp.Diff(tsvOut.String(), wantTSV); diff != "" {
+		t.Errorf("TSV mismatch: got - want +\n%s", diff)
+	}
+}
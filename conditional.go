@@ -0,0 +1,248 @@
+package chainparse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// zstdEncoder is shared by every response, EncodeAll being safe for concurrent use.
+var zstdEncoder, _ = zstd.NewWriter(nil)
+
+// contentEncodings are the content codings that responses can be compressed
+// with, in order of preference when the client accepts several equally.
+var contentEncodings = []struct {
+	name   string
+	encode func([]byte) ([]byte, error)
+}{
+	{"zstd", func(body []byte) ([]byte, error) {
+		return zstdEncoder.EncodeAll(body, nil), nil
+	}},
+	{"br", func(body []byte) ([]byte, error) {
+		var b bytes.Buffer
+		bw := brotli.NewWriter(&b)
+		if _, err := bw.Write(body); err != nil {
+			return nil, err
+		}
+		err := bw.Close()
+		return b.Bytes(), err
+	}},
+	{"gzip", func(body []byte) ([]byte, error) {
+		var b bytes.Buffer
+		gw := gzip.NewWriter(&b)
+		if _, err := gw.Write(body); err != nil {
+			return nil, err
+		}
+		err := gw.Close()
+		return b.Bytes(), err
+	}},
+}
+
+// negotiateEncoding picks the content coding that a request's Accept-Encoding
+// header prefers amongst contentEncodings, or none for the identity.
+func negotiateEncoding(acceptEncoding string) string {
+	q := make(map[string]float64)
+	for _, elem := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(elem, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		if name == "" {
+			continue
+		}
+		weight := 1.0
+		for _, param := range params[1:] {
+			if v := strings.TrimSpace(param); strings.HasPrefix(v, "q=") {
+				if f, err := strconv.ParseFloat(v[2:], 64); err == nil {
+					weight = f
+				}
+			}
+		}
+		q[name] = weight
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range contentEncodings {
+		weight, ok := q[enc.name]
+		if !ok {
+			weight = q["*"]
+		}
+		if weight > bestQ {
+			best, bestQ = enc.name, weight
+		}
+	}
+	return best
+}
+
+// etagMatches reports whether the If-None-Match header lists the ETag,
+// ignoring the content coding suffix of its compressed variants.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+		for _, enc := range contentEncodings {
+			if candidate == strings.TrimSuffix(etag, `"`)+"-"+enc.name+`"` {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// notModified evaluates the conditional headers of req against the ETag of
+// the representation and the time the snapshot was taken, If-None-Match
+// taking precedence over If-Modified-Since.
+func notModified(req *http.Request, etag string, modified time.Time) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, etag)
+	}
+	ims, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !modified.Truncate(time.Second).After(ims)
+}
+
+// incompressibleTypes are the content types that are compressed already,
+// which are served as is whatever the Accept-Encoding.
+var incompressibleTypes = map[string]bool{
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": true,
+	"application/zip": true,
+}
+
+// maxSnapshotResponses bounds how many representations of a snapshot are
+// cached, since the query parameters of /v1/chains have no end of combinations.
+const maxSnapshotResponses = 128
+
+// snapshotResponse is a representation of a snapshot, rendered once and
+// served until the next refresh along with its compressed variants.
+type snapshotResponse struct {
+	contentType        string
+	contentDisposition string
+	body               []byte
+	etag               string
+
+	mu      sync.Mutex
+	encoded map[string][]byte
+}
+
+// cachedResponse returns the representation of snap cached under key, if any.
+func (snap *snapshot) cachedResponse(key string) *snapshotResponse {
+	snap.mu.Lock()
+	defer snap.mu.Unlock()
+	return snap.responses[key]
+}
+
+// cacheResponse caches body under key as a representation of snap, along with
+// the Content-Type and Content-Disposition headers already set in h. It's
+// given a strong ETag hashed from its content.
+func (snap *snapshot) cacheResponse(key string, h http.Header, body []byte) *snapshotResponse {
+	sum := sha256.Sum256(body)
+	resp := &snapshotResponse{
+		contentType:        h.Get("Content-Type"),
+		contentDisposition: h.Get("Content-Disposition"),
+		body:               body,
+		etag:               `"` + hex.EncodeToString(sum[:16]) + `"`,
+	}
+
+	snap.mu.Lock()
+	defer snap.mu.Unlock()
+	if prev := snap.responses[key]; prev != nil {
+		// Another request rendered it in the meantime.
+		return prev
+	}
+	if snap.responses == nil {
+		snap.responses = make(map[string]*snapshotResponse)
+	}
+	if len(snap.responses) < maxSnapshotResponses {
+		snap.responses[key] = resp
+	}
+	return resp
+}
+
+// compressible reports whether the representation is worth compressing.
+func (resp *snapshotResponse) compressible() bool {
+	mediaType, _, _ := strings.Cut(resp.contentType, ";")
+	return !incompressibleTypes[strings.TrimSpace(mediaType)]
+}
+
+// encode returns the body compressed with encoding, compressing it only
+// the first time around.
+func (resp *snapshotResponse) encode(encoding string) ([]byte, error) {
+	resp.mu.Lock()
+	defer resp.mu.Unlock()
+	if body, ok := resp.encoded[encoding]; ok {
+		return body, nil
+	}
+	for _, enc := range contentEncodings {
+		if enc.name != encoding {
+			continue
+		}
+		body, err := enc.encode(resp.body)
+		if err != nil {
+			return nil, err
+		}
+		if resp.encoded == nil {
+			resp.encoded = make(map[string][]byte)
+		}
+		resp.encoded[encoding] = body
+		return body, nil
+	}
+	return resp.body, nil
+}
+
+// serve serves the representation of snap with its ETag and Last-Modified
+// and Age headers. Conditional requests that match get a 304, and it's
+// compressed with zstd, brotli or gzip as negotiated unless it's compressed
+// already. The ETags of the compressed variants carry the content coding,
+// as in "…-gzip".
+func (resp *snapshotResponse) serve(rw http.ResponseWriter, req *http.Request, snap *snapshot) {
+	h := rw.Header()
+	snap.setHeaders(rw, time.Now())
+
+	encoding := ""
+	if resp.compressible() {
+		h.Add("Vary", "Accept-Encoding")
+		encoding = negotiateEncoding(req.Header.Get("Accept-Encoding"))
+	}
+	if encoding != "" {
+		h.Set("ETag", strings.TrimSuffix(resp.etag, `"`)+"-"+encoding+`"`)
+	} else {
+		h.Set("ETag", resp.etag)
+	}
+	if notModified(req, resp.etag, snap.at) {
+		rw.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	body, err := resp.encode(encoding)
+	if err != nil {
+		h.Del("ETag")
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if encoding != "" {
+		h.Set("Content-Encoding", encoding)
+	}
+	h.Set("Content-Type", resp.contentType)
+	if resp.contentDisposition != "" {
+		h.Set("Content-Disposition", resp.contentDisposition)
+	}
+	h.Set("Content-Length", strconv.Itoa(len(body)))
+	rw.WriteHeader(http.StatusOK)
+	if req.Method != http.MethodHead {
+		rw.Write(body)
+	}
+}
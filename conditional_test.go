@@ -0,0 +1,170 @@
+package chainparse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding, want string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"gzip, deflate, br, zstd", "zstd"},
+		{"br;q=0.5, gzip;q=0.8", "gzip"},
+		{"*", "zstd"},
+		{"*, zstd;q=0", "br"},
+		{"identity", ""},
+		{"GZIP;q=1.0", "gzip"},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.acceptEncoding); got != tt.want {
+			t.Errorf("negotiateEncoding(%q): got %q, want %q", tt.acceptEncoding, got, tt.want)
+		}
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	cp := apiChainParser()
+	get := func(header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		cp.FetchData(rec, req)
+		return rec
+	}
+
+	rec := get(nil)
+	if g, w := rec.Code, http.StatusOK; g != w {
+		t.Fatalf("Status: got %d, want %d", g, w)
+	}
+	etag := rec.Header().Get("ETag")
+	if len(etag) != 34 || etag[0] != '"' {
+		t.Fatalf("Expected a strong ETag, got %q", etag)
+	}
+	identity := rec.Body.Bytes()
+	if g := get(nil).Header().Get("ETag"); g != etag {
+		t.Errorf("The same snapshot got another ETag: got %q, want %q", g, etag)
+	}
+
+	tests := []struct {
+		name       string
+		header     http.Header
+		wantStatus int
+	}{
+		{"If-None-Match", http.Header{"If-None-Match": {etag}}, http.StatusNotModified},
+		{"If-None-Match list", http.Header{"If-None-Match": {`"0123", ` + etag}}, http.StatusNotModified},
+		{"If-None-Match another", http.Header{"If-None-Match": {`"0123"`}}, http.StatusOK},
+		{"If-None-Match gzip variant", http.Header{"If-None-Match": {etag[:33] + `-gzip"`}}, http.StatusNotModified},
+		{"If-Modified-Since", http.Header{"If-Modified-Since": {"Sat, 01 Oct 2022 12:00:00 GMT"}}, http.StatusNotModified},
+		{"If-Modified-Since earlier", http.Header{"If-Modified-Since": {"Sat, 01 Oct 2022 11:59:59 GMT"}}, http.StatusOK},
+		{"If-None-Match over If-Modified-Since", http.Header{"If-None-Match": {`"0123"`}, "If-Modified-Since": {"Sat, 01 Oct 2022 12:00:00 GMT"}}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := get(tt.header)
+		if g, w := rec.Code, tt.wantStatus; g != w {
+			t.Errorf("%s: status: got %d, want %d", tt.name, g, w)
+		}
+		if rec.Code == http.StatusNotModified && rec.Body.Len() != 0 {
+			t.Errorf("%s: a 304 can't have a body, got %q", tt.name, rec.Body)
+		}
+		if g := rec.Header().Get("Last-Modified"); g != "Sat, 01 Oct 2022 12:00:00 GMT" {
+			t.Errorf("%s: Last-Modified: got %q", tt.name, g)
+		}
+	}
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+		"zstd": func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+	}
+	for encoding, decode := range decoders {
+		rec := get(http.Header{"Accept-Encoding": {encoding}})
+		if g := rec.Header().Get("Content-Encoding"); g != encoding {
+			t.Errorf("%s: Content-Encoding: got %q", encoding, g)
+			continue
+		}
+		if g, w := rec.Header().Get("ETag"), etag[:33]+"-"+encoding+`"`; g != w {
+			t.Errorf("%s: ETag: got %q, want %q", encoding, g, w)
+		}
+		if g := rec.Header().Get("Vary"); g != "Accept-Encoding" {
+			t.Errorf("%s: Vary: got %q", encoding, g)
+		}
+		r, err := decode(rec.Body)
+		if err != nil {
+			t.Fatalf("%s: %v", encoding, err)
+		}
+		body, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s: %v", encoding, err)
+		}
+		if !bytes.Equal(body, identity) {
+			t.Errorf("%s: the decoded body differs:\n%s", encoding, body)
+		}
+	}
+}
+
+func TestSnapshotResponses(t *testing.T) {
+	cp := apiChainParser()
+	serve := func(hf http.HandlerFunc, target, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		rec := httptest.NewRecorder()
+		hf(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status: got %d, want %d", target, rec.Code, http.StatusOK)
+		}
+		return rec
+	}
+
+	first := serve(cp.Chains, "/v1/chains?sort=chain_name&limit=2", "gzip")
+	snap := cp.fetcher.snap
+	resp := snap.cachedResponse("/v1/chains?limit=2&sort=chain_name")
+	if resp == nil {
+		t.Fatal("The response wasn't cached")
+	}
+	if _, ok := resp.encoded["gzip"]; !ok {
+		t.Error("The gzip variant wasn't cached")
+	}
+
+	// The cached response is served as is until the next snapshot, whatever
+	// the order of the query parameters.
+	snap.res = new(Result)
+	again := serve(cp.Chains, "/v1/chains?limit=2&sort=chain_name", "gzip")
+	if !bytes.Equal(again.Body.Bytes(), first.Body.Bytes()) {
+		t.Error("The cached response wasn't served")
+	}
+	if g, w := again.Header().Get("ETag"), first.Header().Get("ETag"); g != w {
+		t.Errorf("ETag: got %q, want %q", g, w)
+	}
+	if g, w := len(snap.responses), 1; g != w {
+		t.Errorf("Cached responses: got %d, want %d", g, w)
+	}
+
+	// XLSX files are zipped already, so they're never compressed.
+	snap.res = reportResult
+	rec := serve(cp.FetchReport, "/report?format=xlsx", "zstd, br, gzip")
+	if g := rec.Header().Get("Content-Encoding"); g != "" {
+		t.Errorf("XLSX: Content-Encoding: got %q, want none", g)
+	}
+	if g := rec.Header().Get("Vary"); g != "" {
+		t.Errorf("XLSX: Vary: got %q, want none", g)
+	}
+	if g := rec.Header().Get("Content-Disposition"); g != `attachment; filename="chainparse.xlsx"` {
+		t.Errorf("XLSX: Content-Disposition: got %q", g)
+	}
+	if !bytes.HasPrefix(rec.Body.Bytes(), []byte("PK")) {
+		t.Error("XLSX: expected a zip file")
+	}
+}
@@ -0,0 +1,302 @@
+package chainparse
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The modules that changes are tracked for, besides the tracked dependencies.
+const moduleRecommendedVersion = "recommended_version"
+
+// FeedModules are the modules whose changes are reported in the feed.
+var FeedModules = []string{moduleRecommendedVersion, moduleCosmosSDK, moduleTendermint, moduleIBC}
+
+// maxChanges bounds how many changes a ChangeLog keeps, the oldest going first.
+const maxChanges = 1000
+
+// Change is a change of a chain's recommended version or of the version of
+// one of its tracked dependencies between two runs.
+type Change struct {
+	ChainName  string `json:"chain_name"`
+	PrettyName string `json:"pretty_name,omitempty"`
+
+	// Module is one of FeedModules.
+	Module string    `json:"module"`
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	At     time.Time `json:"at"`
+}
+
+// ChangeLog remembers the versions of every chain so that the changes from
+// one run to the next can be recorded.
+type ChangeLog struct {
+	// Versions maps every chain ever analysed successfully to the last
+	// known version of each of FeedModules.
+	Versions map[string]map[string]string `json:"versions"`
+
+	// Changes holds the most recent changes, oldest first.
+	Changes []*Change `json:"changes"`
+}
+
+// LoadChangeLog reads the ChangeLog saved at path, or returns an empty one
+// if there's no such file yet.
+func LoadChangeLog(path string) (*ChangeLog, error) {
+	cl := new(ChangeLog)
+	blob, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cl, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(blob, cl); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cl, nil
+}
+
+// Save writes the ChangeLog to path.
+func (cl *ChangeLog) Save(path string) error {
+	blob, err := json.MarshalIndent(cl, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, blob, 0o644)
+}
+
+func feedVersions(cs *ChainSchema) map[string]string {
+	versions := map[string]string{
+		moduleCosmosSDK:  cs.CosmosSDKVersion,
+		moduleTendermint: cs.TendermintVersion,
+		moduleIBC:        cs.IBCVersion,
+	}
+	if cs.Codebase != nil {
+		versions[moduleRecommendedVersion] = cs.Codebase.RecommendedVersion
+	}
+	return versions
+}
+
+// Record records the changes since the last recorded run and returns them.
+// Chains that failed in res keep their previous versions, so that a chain
+// coming back doesn't show up as changed.
+func (cl *ChangeLog) Record(res *Result, at time.Time) []*Change {
+	if cl.Versions == nil {
+		cl.Versions = make(map[string]map[string]string)
+	}
+	var changes []*Change
+	for _, cs := range res.Chains {
+		cur := feedVersions(cs)
+		if prev, ok := cl.Versions[cs.ChainName]; ok {
+			for _, module := range FeedModules {
+				if prev[module] != cur[module] {
+					changes = append(changes, &Change{
+						ChainName:  cs.ChainName,
+						PrettyName: cs.PrettyName,
+						Module:     module,
+						From:       prev[module],
+						To:         cur[module],
+						At:         at.UTC(),
+					})
+				}
+			}
+		}
+		cl.Versions[cs.ChainName] = cur
+	}
+	cl.Changes = append(cl.Changes, changes...)
+	if n := len(cl.Changes); n > maxChanges {
+		cl.Changes = append([]*Change(nil), cl.Changes[n-maxChanges:]...)
+	}
+	return changes
+}
+
+// FeedFilter selects changes by chain and module, an empty list selecting all.
+type FeedFilter struct {
+	Chains  []string
+	Modules []string
+}
+
+// ParseFeedFilter parses comma separated lists of chain names and of
+// modules, each one of FeedModules or cometbft for tendermint.
+func ParseFeedFilter(chains, modules string) (FeedFilter, error) {
+	var f FeedFilter
+	split := func(list string) []string {
+		var elems []string
+		for _, elem := range strings.Split(list, ",") {
+			if elem = strings.TrimSpace(elem); elem != "" {
+				elems = append(elems, elem)
+			}
+		}
+		return elems
+	}
+	f.Chains = split(chains)
+	for _, module := range split(modules) {
+		if module == "cometbft" {
+			module = moduleTendermint
+		}
+		if !stringsContain(FeedModules, module) {
+			return f, fmt.Errorf("unknown module %q, expected any of %q or cometbft", module, FeedModules)
+		}
+		f.Modules = append(f.Modules, module)
+	}
+	return f, nil
+}
+
+func stringsContain(list []string, s string) bool {
+	for _, elem := range list {
+		if elem == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether ch is selected by the filter.
+func (f FeedFilter) Match(ch *Change) bool {
+	return (len(f.Chains) == 0 || stringsContain(f.Chains, ch.ChainName)) &&
+		(len(f.Modules) == 0 || stringsContain(f.Modules, ch.Module))
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title    string       `xml:"title"`
+	ID       string       `xml:"id"`
+	Updated  string       `xml:"updated"`
+	Category atomCategory `xml:"category"`
+	Summary  string       `xml:"summary"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// atomModuleNames are the human readable names of FeedModules.
+var atomModuleNames = map[string]string{
+	moduleRecommendedVersion: "recommended version",
+	moduleCosmosSDK:          "Cosmos SDK",
+	moduleTendermint:         "Tendermint",
+	moduleIBC:                "IBC",
+}
+
+// WriteAtomFeed writes the changes selected by f as an Atom feed, newest first.
+// selfURL is the URL that the feed is served at, if any, and updated is
+// reported as the feed's update time if there are no changes.
+func WriteAtomFeed(w io.Writer, changes []*Change, f FeedFilter, selfURL string, updated time.Time) error {
+	feed := &atomFeed{
+		Title:  "Cosmos chain version changes",
+		ID:     "tag:chainparse,2022:feed",
+		Author: atomAuthor{Name: "chainparse"},
+	}
+	if selfURL != "" {
+		feed.ID = selfURL
+		feed.Links = append(feed.Links, atomLink{Rel: "self", Href: selfURL})
+	}
+	for i := len(changes) - 1; i >= 0; i-- {
+		ch := changes[i]
+		if !f.Match(ch) {
+			continue
+		}
+		name := ch.PrettyName
+		if name == "" {
+			name = ch.ChainName
+		}
+		from, to := ch.From, ch.To
+		if from == "" {
+			from = "none"
+		}
+		if to == "" {
+			to = "none"
+		}
+		at := ch.At.UTC().Format(time.RFC3339)
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:    fmt.Sprintf("%s: %s %s → %s", name, atomModuleNames[ch.Module], from, to),
+			ID:       fmt.Sprintf("tag:chainparse,%s:%s/%s/%s", ch.At.UTC().Format("2006-01-02"), ch.ChainName, ch.Module, at),
+			Updated:  at,
+			Category: atomCategory{Term: ch.Module},
+			Summary:  fmt.Sprintf("The %s of %s changed from %s to %s.", atomModuleNames[ch.Module], ch.ChainName, from, to),
+		})
+		if feed.Updated == "" {
+			feed.Updated = at
+		}
+	}
+	if feed.Updated == "" {
+		feed.Updated = updated.UTC().Format(time.RFC3339)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WithChangeLog makes the server's feed carry on from cl, as loaded from
+// path with LoadChangeLog, and saves it back to path after every refresh,
+// so that the feed survives restarts and the first refresh after one
+// reports the changes since the last run.
+func WithChangeLog(cl *ChangeLog, path string) Option {
+	return func(fr *fetcher) {
+		fr.changes = cl
+		fr.changesPath = path
+	}
+}
+
+// Feed serves /feed.atom, the Atom feed of the changes between the server's
+// refreshes, optionally filtered by the chain and module query parameters,
+// each a comma separated list.
+func (cp *ChainParser) Feed(rw http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	f, err := ParseFeedFilter(query.Get("chain"), query.Get("module"))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fr := cp.fetcher
+	fr.mu.Lock()
+	changes := append([]*Change(nil), fr.changes.Changes...)
+	fr.mu.Unlock()
+
+	selfURL := *req.URL
+	if selfURL.Host == "" {
+		selfURL.Scheme, selfURL.Host = "http", req.Host
+		if req.TLS != nil {
+			selfURL.Scheme = "https"
+		}
+	}
+	rw.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	if err := WriteAtomFeed(rw, changes, f, selfURL.String(), time.Now()); err != nil {
+		logrus.WithContext(req.Context()).WithError(err).Error("failed to send the feed")
+	}
+}
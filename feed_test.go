@@ -0,0 +1,162 @@
+package chainparse
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestChangeLog(t *testing.T) {
+	cl := new(ChangeLog)
+	if changes := cl.Record(reportResult, reportTime); len(changes) != 0 {
+		t.Fatalf("The first run can't have changes, got %d", len(changes))
+	}
+
+	hub := *reportResult.Chains[1]
+	hub.Codebase = &Codebase{GitRepoURL: hub.Codebase.GitRepoURL, RecommendedVersion: "v8.0.0"}
+	hub.CosmosSDKVersion = "v0.45.9"
+	// Agoric failed this time round, it mustn't be reported as changed.
+	next := &Result{Chains: []*ChainSchema{&hub, reportResult.Chains[2]}}
+	at := reportTime.Add(24 * time.Hour)
+	got := cl.Record(next, at)
+	want := []*Change{
+		{ChainName: "cosmoshub", PrettyName: "Cosmos | Hub", Module: "recommended_version", From: "v7.0.0", To: "v8.0.0", At: at},
+		{ChainName: "cosmoshub", PrettyName: "Cosmos | Hub", Module: "cosmos-sdk", From: "v0.45.4", To: "v0.45.9", At: at},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("Changes mismatch: got - want +\n%s", diff)
+	}
+	if changes := cl.Record(reportResult, at.Add(time.Hour)); len(changes) != 2 {
+		t.Errorf("Going back to the first run's versions should be 2 changes, got %d", len(changes))
+	}
+
+	path := filepath.Join(t.TempDir(), "changes.json")
+	if err := cl.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadChangeLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(loaded, cl); diff != "" {
+		t.Errorf("Loaded ChangeLog mismatch: got - want +\n%s", diff)
+	}
+	if _, err := LoadChangeLog(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("A missing ChangeLog should be empty, got %v", err)
+	}
+}
+
+func TestFeed(t *testing.T) {
+	cp := NewChainParser(nil)
+	cp.fetcher.changes = &ChangeLog{Changes: []*Change{
+		{ChainName: "cosmoshub", PrettyName: "Cosmos Hub", Module: "cosmos-sdk", From: "v0.45.4", To: "v0.45.9", At: reportTime},
+		{ChainName: "agoric", Module: "ibc-go", From: "v1.2.0", To: "", At: reportTime.Add(time.Hour)},
+		{ChainName: "cosmoshub", PrettyName: "Cosmos Hub", Module: "recommended_version", From: "v7.0.0", To: "v8.0.0", At: reportTime.Add(2 * time.Hour)},
+	}}
+
+	type entry struct {
+		Title    string `xml:"title"`
+		Category struct {
+			Term string `xml:"term,attr"`
+		} `xml:"category"`
+	}
+	var feed struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string   `xml:"id"`
+		Updated string   `xml:"updated"`
+		Entries []entry  `xml:"entry"`
+	}
+
+	rec := httptest.NewRecorder()
+	cp.Feed(rec, httptest.NewRequest("GET", "http://chainparse.example.com/feed.atom?chain=cosmoshub", nil))
+	if g, w := rec.Header().Get("Content-Type"), "application/atom+xml; charset=utf-8"; g != w {
+		t.Errorf("Content-Type: got %q, want %q", g, w)
+	}
+	if err := xml.Unmarshal(rec.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("Invalid feed: %v\n%s", err, rec.Body)
+	}
+	if g, w := feed.ID, "http://chainparse.example.com/feed.atom?chain=cosmoshub"; g != w {
+		t.Errorf("ID: got %q, want %q", g, w)
+	}
+	if g, w := feed.Updated, "2022-10-01T14:00:00Z"; g != w {
+		t.Errorf("Updated: got %q, want %q", g, w)
+	}
+	var titles []string
+	for _, e := range feed.Entries {
+		titles = append(titles, e.Title)
+	}
+	wantTitles := []string{
+		"Cosmos Hub: recommended version v7.0.0 → v8.0.0",
+		"Cosmos Hub: Cosmos SDK v0.45.4 → v0.45.9",
+	}
+	if diff := cmp.Diff(titles, wantTitles); diff != "" {
+		t.Errorf("Entries mismatch: got - want +\n%s", diff)
+	}
+
+	rec = httptest.NewRecorder()
+	cp.Feed(rec, httptest.NewRequest("GET", "/feed.atom?module=ibc-go", nil))
+	if !strings.Contains(rec.Body.String(), "<title>agoric: IBC v1.2.0 → none</title>") || strings.Contains(rec.Body.String(), "Cosmos Hub") {
+		t.Errorf("Filtering by module failed:\n%s", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	cp.Feed(rec, httptest.NewRequest("GET", "/feed.atom?module=wasmd", nil))
+	if g, w := rec.Code, 400; g != w {
+		t.Errorf("Unknown module: got status %d, want %d", g, w)
+	}
+}
+
+func TestWithChangeLog(t *testing.T) {
+	var goMod atomic.Value
+	goMod.Store(testdataGoMod)
+	cst := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "go.mod"):
+			rw.Write(goMod.Load().([]byte))
+		case strings.HasSuffix(req.URL.Path, "master.zip"):
+			rw.Write(testdataZip)
+		default:
+			http.NotFound(rw, req)
+		}
+	}))
+	defer cst.Close()
+	destURL, _ := url.Parse(cst.URL)
+	art := &alwaysToURLRoundTripper{next: cst.Client(), destURL: destURL}
+	path := filepath.Join(t.TempDir(), "changes.json")
+
+	// A server restarted after a run carries on from the saved change log.
+	for i, sdk := range []string{"v0.44.2-alpha.agoric.gaiad.1", "v0.44.3-alpha.agoric.gaiad.2"} {
+		goMod.Store(bytes.ReplaceAll(testdataGoMod, []byte("v0.44.2-alpha.agoric.gaiad.1"), []byte(sdk)))
+		cl, err := LoadChangeLog(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fr := newFetcher(art, WithChangeLog(cl, path))
+		if _, err := fr.fetchResult(context.Background()); err != nil {
+			t.Fatalf("Run %d: %v", i, err)
+		}
+	}
+
+	cl, err := LoadChangeLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cl.Changes) == 0 {
+		t.Fatal("The change across the restart wasn't recorded")
+	}
+	for _, ch := range cl.Changes {
+		if ch.Module != moduleCosmosSDK || ch.To != "v0.44.3-alpha.agoric.gaiad.2@github.com/agoric-labs/cosmos-sdk" {
+			t.Errorf("Unexpected change %+v", ch)
+		}
+	}
+}
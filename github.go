@@ -0,0 +1,375 @@
+package chainparse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/v47/github"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/trace"
+)
+
+const (
+	githubAPIURL     = "https://api.github.com"
+	githubGraphQLURL = "https://api.github.com/graphql"
+
+	// GitHubTokenEnv and GitHubTokenFileEnv name the environment variables
+	// from which a GitHub token is read, the former holding the token itself
+	// and the latter a path to a file containing it.
+	GitHubTokenEnv     = "GITHUB_TOKEN"
+	GitHubTokenFileEnv = "GITHUB_TOKEN_FILE"
+
+	// GitHub caps the cost of a single GraphQL query, each repository
+	// costs a node per field so keep batches comfortably under that.
+	githubGraphQLBatchSize = 40
+)
+
+// LoadGitHubToken returns the GitHub token read from tokenFile or, if tokenFile
+// is empty, from the environment where GITHUB_TOKEN takes precedence over
+// GITHUB_TOKEN_FILE. An empty token with a nil error is returned if no token
+// was configured at all.
+func LoadGitHubToken(tokenFile string) (string, error) {
+	if tokenFile != "" {
+		return readGitHubTokenFile(tokenFile)
+	}
+	if token := strings.TrimSpace(os.Getenv(GitHubTokenEnv)); token != "" {
+		return token, nil
+	}
+	if tokenFile = os.Getenv(GitHubTokenFileEnv); tokenFile != "" {
+		return readGitHubTokenFile(tokenFile)
+	}
+	return "", nil
+}
+
+func readGitHubTokenFile(tokenFile string) (string, error) {
+	blob, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(blob))
+	if token == "" {
+		return "", fmt.Errorf("GitHub token file %q is empty", tokenFile)
+	}
+	return token, nil
+}
+
+// githubRepo is the subset of a GitHub repository's metadata that chainparse uses.
+type githubRepo struct {
+	NameWithOwner string
+	DefaultBranch string
+	LatestRelease string
+	IsArchived    bool
+
+	// Releases holds the newest releases, excluding drafts,
+	// it is nil if the releases weren't requested.
+	Releases []githubRelease
+
+	// GoMods maps the git ref that go.mod was looked up at, to its contents.
+	// A ref that is absent either was not requested or has no go.mod file.
+	GoMods map[string][]byte
+}
+
+type githubRelease struct {
+	TagName string
+	Name    string
+}
+
+// githubRepoRequest asks for a repository's metadata plus the go.mod files at refs.
+type githubRepoRequest struct {
+	Owner    string
+	Name     string
+	Refs     []string
+	Releases bool
+}
+
+// githubRepoEntry returns the cache entry of repo, creating it if there's
+// none yet. fr.mu must be held.
+func (fr *fetcher) githubRepoEntry(repo RepoID) *githubRepo {
+	key := repo.Key()
+	ghRepo := fr.repoCache[key]
+	if ghRepo == nil {
+		ghRepo = &githubRepo{NameWithOwner: repo.Owner + "/" + repo.Repo, GoMods: make(map[string][]byte)}
+		fr.repoCache[key] = ghRepo
+	}
+	return ghRepo
+}
+
+func (fr *fetcher) cachedGitHubRepo(repo RepoID) *githubRepo {
+	if !repo.IsGitHub() {
+		return nil
+	}
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.repoCache[repo.Key()]
+}
+
+// prefetchGitHubRepos populates the repository cache for every chain hosted
+// on GitHub using batched GraphQL queries, so that run can avoid issuing a
+// request per repository. It is a no-op without a GitHub token since the
+// GraphQL API does not permit unauthenticated access.
+func (fr *fetcher) prefetchGitHubRepos(ctx context.Context, csL []*ChainSchema) error {
+	if fr.githubToken == "" {
+		return nil
+	}
+
+	ctx, span := trace.StartSpan(ctx, "prefetchGitHubRepos")
+	defer span.End()
+
+	byKey := make(map[string]*githubRepoRequest)
+	var reqs []*githubRepoRequest
+	for _, cs := range csL {
+		repo, _, err := NormalizeRepoURL(cs.Codebase.GitRepoURL)
+		if err != nil || !repo.IsGitHub() {
+			continue
+		}
+		owner, name := repo.Owner, repo.Repo
+		key := repo.Key()
+		req := byKey[key]
+		if req == nil {
+			req = &githubRepoRequest{
+				Owner:    owner,
+				Name:     name,
+				Refs:     []string{"HEAD"},
+				Releases: fr.discoversReleasesFrom(ReleaseSourceGitHub),
+			}
+			byKey[key] = req
+			reqs = append(reqs, req)
+		}
+		refs := []string{cs.Codebase.RecommendedVersion}
+		if fr.compatibleVersions {
+			refs = chainVersions(cs.Codebase)
+		}
+	nextRef:
+		for _, ref := range refs {
+			if ref == "" {
+				continue
+			}
+			for _, seen := range req.Refs {
+				if seen == ref {
+					continue nextRef
+				}
+			}
+			req.Refs = append(req.Refs, ref)
+		}
+	}
+
+	client := &http.Client{Transport: fr.rt}
+	for i := 0; i < len(reqs); i += githubGraphQLBatchSize {
+		j := i + githubGraphQLBatchSize
+		if j > len(reqs) {
+			j = len(reqs)
+		}
+		if err := fr.githubGraphQLRepos(ctx, client, reqs[i:j]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type githubGraphQLRequest struct {
+	Query     string            `json:"query"`
+	Variables map[string]string `json:"variables"`
+}
+
+type githubGraphQLBlob struct {
+	Text *string `json:"text"`
+}
+
+type githubGraphQLRepo struct {
+	NameWithOwner    string `json:"nameWithOwner"`
+	IsArchived       bool   `json:"isArchived"`
+	DefaultBranchRef *struct {
+		Name string `json:"name"`
+	} `json:"defaultBranchRef"`
+	LatestRelease *struct {
+		TagName string `json:"tagName"`
+	} `json:"latestRelease"`
+	Releases *struct {
+		Nodes []struct {
+			TagName string `json:"tagName"`
+			Name    string `json:"name"`
+			IsDraft bool   `json:"isDraft"`
+		} `json:"nodes"`
+	} `json:"releases"`
+}
+
+type githubGraphQLResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Type    string        `json:"type"`
+		Path    []interface{} `json:"path"`
+		Message string        `json:"message"`
+	} `json:"errors"`
+}
+
+// buildGitHubGraphQLQuery returns a single query that looks up every repository
+// in reqs under the aliases r0, r1, ..., with each requested go.mod file under
+// the aliases m0, m1, ... within its repository. All user supplied values are
+// passed as variables so that no escaping of the query text is necessary.
+func buildGitHubGraphQLQuery(reqs []*githubRepoRequest) *githubGraphQLRequest {
+	var params, body strings.Builder
+	vars := make(map[string]string)
+	for i, req := range reqs {
+		ov, nv := fmt.Sprintf("o%d", i), fmt.Sprintf("n%d", i)
+		vars[ov], vars[nv] = req.Owner, req.Name
+		fmt.Fprintf(&params, "$%s: String!, $%s: String!, ", ov, nv)
+		fmt.Fprintf(&body, "  r%d: repository(owner: $%s, name: $%s) {\n", i, ov, nv)
+		body.WriteString("    nameWithOwner\n    isArchived\n")
+		body.WriteString("    defaultBranchRef { name }\n    latestRelease { tagName }\n")
+		if req.Releases {
+			body.WriteString("    releases(first: 100, orderBy: {field: CREATED_AT, direction: DESC}) { nodes { tagName name isDraft } }\n")
+		}
+		for j, ref := range req.Refs {
+			ev := fmt.Sprintf("e%d_%d", i, j)
+			vars[ev] = ref + ":go.mod"
+			fmt.Fprintf(&params, "$%s: String!, ", ev)
+			fmt.Fprintf(&body, "    m%d: object(expression: $%s) { ... on Blob { text } }\n", j, ev)
+		}
+		body.WriteString("  }\n")
+	}
+	query := "query(" + strings.TrimSuffix(params.String(), ", ") + ") {\n" + body.String() + "}"
+	return &githubGraphQLRequest{Query: query, Variables: vars}
+}
+
+// githubGraphQLRepos looks up a batch of repositories, storing them in the cache.
+func (fr *fetcher) githubGraphQLRepos(ctx context.Context, client *http.Client, reqs []*githubRepoRequest) error {
+	blob, err := json.Marshal(buildGitHubGraphQLQuery(reqs))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", githubGraphQLURL, bytes.NewReader(blob))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "bearer "+fr.githubToken)
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	blob, err = io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("GitHub GraphQL request failed with status: %q: %s", res.Status, blob)
+	}
+
+	gres := new(githubGraphQLResponse)
+	if err := json.Unmarshal(blob, gres); err != nil {
+		return err
+	}
+	// Errors such as NOT_FOUND for a single repository don't invalidate
+	// the rest of the batch, so only log them.
+	for _, gerr := range gres.Errors {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"type": gerr.Type,
+			"path": gerr.Path,
+		}).Error(gerr.Message)
+	}
+	if len(gres.Data) == 0 && len(gres.Errors) != 0 {
+		return errors.New(gres.Errors[0].Message)
+	}
+
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	for i, greq := range reqs {
+		raw := gres.Data[fmt.Sprintf("r%d", i)]
+		if len(raw) == 0 || string(raw) == "null" {
+			continue
+		}
+		grepo := new(githubGraphQLRepo)
+		if err := json.Unmarshal(raw, grepo); err != nil {
+			return err
+		}
+		repo := fr.githubRepoEntry(RepoID{Host: "github.com", Owner: greq.Owner, Repo: greq.Name})
+		repo.NameWithOwner = grepo.NameWithOwner
+		repo.IsArchived = grepo.IsArchived
+		if grepo.DefaultBranchRef != nil {
+			repo.DefaultBranch = grepo.DefaultBranchRef.Name
+		}
+		if grepo.LatestRelease != nil {
+			repo.LatestRelease = grepo.LatestRelease.TagName
+		}
+		if grepo.Releases != nil {
+			repo.Releases = make([]githubRelease, 0, len(grepo.Releases.Nodes))
+			for _, node := range grepo.Releases.Nodes {
+				if !node.IsDraft {
+					repo.Releases = append(repo.Releases, githubRelease{TagName: node.TagName, Name: node.Name})
+				}
+			}
+		}
+		fields := make(map[string]json.RawMessage)
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return err
+		}
+		for j, ref := range greq.Refs {
+			gblob := new(githubGraphQLBlob)
+			if err := json.Unmarshal(fields[fmt.Sprintf("m%d", j)], gblob); err != nil || gblob.Text == nil {
+				continue
+			}
+			repo.GoMods[ref] = []byte(*gblob.Text)
+		}
+	}
+	return nil
+}
+
+func (fr *fetcher) githubFetchDefaultBranchForRepo(ctx context.Context, client *http.Client, repo RepoID) (string, error) {
+	// 1. Firstly check if the repository was cached or not.
+	if ghRepo := fr.cachedGitHubRepo(repo); ghRepo != nil && ghRepo.DefaultBranch != "" {
+		return ghRepo.DefaultBranch, nil
+	}
+
+	if !repo.IsGitHub() {
+		return "", fmt.Errorf("not a GitHub repository: %q", repo)
+	}
+	owner, name := repo.Owner, repo.Repo
+	apiURL := githubAPIURL + path.Join("/repos", owner, name)
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if fr.githubToken != "" {
+		req.Header.Set("Authorization", "Bearer "+fr.githubToken)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	blob, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		errStr := res.Status
+		if len(blob) != 0 {
+			errStr = string(blob)
+		}
+		return "", errors.New(errStr)
+	}
+
+	grepo := new(github.Repository)
+	if err := json.Unmarshal(blob, grepo); err != nil {
+		return "", err
+	}
+
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	ghRepo := fr.githubRepoEntry(repo)
+	ghRepo.NameWithOwner = grepo.GetFullName()
+	ghRepo.DefaultBranch = grepo.GetDefaultBranch()
+	ghRepo.IsArchived = grepo.GetArchived()
+
+	return ghRepo.DefaultBranch, nil
+}
@@ -0,0 +1,133 @@
+package chainparse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadGitHubToken(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("  file-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(GitHubTokenEnv, "")
+	t.Setenv(GitHubTokenFileEnv, "")
+	if token, err := LoadGitHubToken(""); err != nil || token != "" {
+		t.Fatalf("Unconfigured: got (%q, %v), want no token and no error", token, err)
+	}
+
+	t.Setenv(GitHubTokenFileEnv, tokenFile)
+	if token, err := LoadGitHubToken(""); err != nil || token != "file-token" {
+		t.Fatalf("From %s: got (%q, %v)", GitHubTokenFileEnv, token, err)
+	}
+
+	t.Setenv(GitHubTokenEnv, "env-token")
+	if token, err := LoadGitHubToken(""); err != nil || token != "env-token" {
+		t.Fatalf("%s should take precedence: got (%q, %v)", GitHubTokenEnv, token, err)
+	}
+	if token, err := LoadGitHubToken(tokenFile); err != nil || token != "file-token" {
+		t.Fatalf("An explicit file should take precedence: got (%q, %v)", token, err)
+	}
+}
+
+func TestPrefetchGitHubRepos(t *testing.T) {
+	recorded, err := os.ReadFile("./testdata/github/graphql.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var graphqlRequests int
+	cst := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/graphql" {
+			// Everything must have been served by the batched lookup.
+			t.Errorf("Unexpected request for %q", req.URL)
+			http.NotFound(rw, req)
+			return
+		}
+		graphqlRequests++
+		if g, w := req.Header.Get("Authorization"), "bearer test-token"; g != w {
+			t.Errorf("Authorization mismatch: got %q, want %q", g, w)
+		}
+		greq := new(githubGraphQLRequest)
+		if err := json.NewDecoder(req.Body).Decode(greq); err != nil {
+			t.Error(err)
+		}
+		wantVars := map[string]string{
+			"o0": "Agoric", "n0": "ag0", "e0_0": "HEAD:go.mod", "e0_1": "agoric-3.1:go.mod",
+			"o1": "AIOZNetwork", "n1": "go-aioz", "e1_0": "HEAD:go.mod", "e1_1": "v1.2.0:go.mod",
+			"o2": "vidulum", "n2": "mainnet", "e2_0": "HEAD:go.mod", "e2_1": "v1.0.0:go.mod",
+		}
+		if diff := cmp.Diff(greq.Variables, wantVars); diff != "" {
+			t.Errorf("Variables mismatch: got - want +\n%s", diff)
+		}
+		rw.Write(recorded)
+	}))
+	defer cst.Close()
+
+	destURL, err := url.Parse(cst.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	art := &alwaysToURLRoundTripper{next: cst.Client(), destURL: destURL}
+	fr := newFetcher(art, WithGitHubToken("test-token"))
+
+	csL := []*ChainSchema{
+		{ChainName: "agoric", NetworkType: "mainnet", Codebase: &Codebase{GitRepoURL: "https://github.com/Agoric/ag0/", RecommendedVersion: "agoric-3.1"}},
+		{ChainName: "aioz", NetworkType: "mainnet", Codebase: &Codebase{GitRepoURL: "https://github.com/AIOZNetwork/go-aioz", RecommendedVersion: "v1.2.0"}},
+		{ChainName: "vidulum", NetworkType: "mainnet", Codebase: &Codebase{GitRepoURL: "https://github.com/vidulum/mainnet", RecommendedVersion: "v1.0.0"}},
+		{ChainName: "thorchain", NetworkType: "mainnet", Codebase: &Codebase{GitRepoURL: "https://gitlab.com/thorchain/thornode", RecommendedVersion: "chaosnet-multichain"}},
+	}
+	ctx := context.Background()
+	if err := fr.prefetchGitHubRepos(ctx, csL); err != nil {
+		t.Fatal(err)
+	}
+	if g, w := graphqlRequests, 1; g != w {
+		t.Fatalf("GraphQL requests: got %d, want %d", g, w)
+	}
+
+	agoric := fr.cachedGitHubRepo(RepoID{Host: "github.com", Owner: "Agoric", Repo: "ag0"})
+	if agoric == nil {
+		t.Fatal("Agoric/ag0 was not cached")
+	}
+	if g, w := agoric.DefaultBranch, "Agoric"; g != w {
+		t.Errorf("Default branch: got %q, want %q", g, w)
+	}
+	if g, w := agoric.LatestRelease, "agoric-upgrade-8"; g != w {
+		t.Errorf("Latest release: got %q, want %q", g, w)
+	}
+	if !bytes.Equal(agoric.GoMods["agoric-3.1"], testdataGoMod) {
+		t.Error("go.mod at agoric-3.1 mismatch")
+	}
+	if !bytes.Equal(agoric.GoMods["HEAD"], testdataLatestGoMod) {
+		t.Error("go.mod at HEAD mismatch")
+	}
+	if repo := fr.cachedGitHubRepo(RepoID{Host: "github.com", Owner: "AIOZNetwork", Repo: "go-aioz"}); repo != nil {
+		t.Errorf("A repository that wasn't found should not be cached, got %#v", repo)
+	}
+	vidulum := fr.cachedGitHubRepo(RepoID{Host: "github.com", Owner: "vidulum", Repo: "mainnet"})
+	if vidulum == nil || !vidulum.IsArchived || len(vidulum.GoMods) != 0 {
+		t.Errorf("vidulum/mainnet should be archived without any go.mod files, got %#v", vidulum)
+	}
+
+	// The go.mod must now be served from the cache and not via raw.githubusercontent.com.
+	cs, _, err := fr.run(ctx, *csL[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, w := cs.CosmosSDKVersion, "v0.44.2-alpha.agoric.gaiad.1@github.com/agoric-labs/cosmos-sdk"; g != w {
+		t.Errorf("Cosmos SDK version: got %q, want %q", g, w)
+	}
+	if g, w := cs.LatestRelease, "agoric-upgrade-8"; g != w {
+		t.Errorf("Latest release: got %q, want %q", g, w)
+	}
+}
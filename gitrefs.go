@@ -0,0 +1,199 @@
+package chainparse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opencensus.io/trace"
+)
+
+// gitRefs is a remote repository's ref advertisement, the
+// equivalent of what `git ls-remote --symref <URL>` prints.
+type gitRefs struct {
+	// Head is the ref that HEAD points to e.g. "refs/heads/main",
+	// it is empty if the server didn't advertise the symref.
+	Head string
+
+	// Refs maps every advertised ref name to its object ID.
+	// For annotated tags, Refs holds the tag object's ID
+	// while Peeled holds the ID of the commit it points to.
+	Refs   map[string]string
+	Peeled map[string]string
+}
+
+// DefaultBranch returns HEAD's target without the "refs/heads/" prefix.
+func (gr *gitRefs) DefaultBranch() string {
+	return strings.TrimPrefix(gr.Head, "refs/heads/")
+}
+
+// Tags returns the names of all the advertised tags without the "refs/tags/" prefix.
+func (gr *gitRefs) Tags() []string {
+	return gr.namesWithPrefix("refs/tags/")
+}
+
+// Branches returns the names of all the advertised branches without the "refs/heads/" prefix.
+func (gr *gitRefs) Branches() []string {
+	return gr.namesWithPrefix("refs/heads/")
+}
+
+func (gr *gitRefs) namesWithPrefix(prefix string) []string {
+	var names []string
+	for ref := range gr.Refs {
+		if strings.HasPrefix(ref, prefix) {
+			names = append(names, strings.TrimPrefix(ref, prefix))
+		}
+	}
+	return names
+}
+
+var errNotSmartHTTP = errors.New("server does not speak the git smart HTTP protocol")
+
+// lsRemote retrieves the refs advertised by the git smart HTTP server of repo
+// via GET $URL/info/refs?service=git-upload-pack. Unlike `git clone` this
+// needs neither a git binary, nor disk space, nor a negotiation for objects.
+// The results are cached per repository until the next refresh.
+func (fr *fetcher) lsRemote(ctx context.Context, repo RepoID) (*gitRefs, error) {
+	cacheKey := repo.Key()
+	repoURL := repo.URL()
+	fr.mu.Lock()
+	refs, ok := fr.refsCache[cacheKey]
+	fr.mu.Unlock()
+	if ok {
+		return refs, nil
+	}
+
+	ctx, span := trace.StartSpan(ctx, "lsRemote")
+	defer span.End()
+
+	infoRefsURL := strings.TrimSuffix(repoURL, "/") + "/info/refs?service=git-upload-pack"
+	req, err := http.NewRequestWithContext(ctx, "GET", infoRefsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Some forges only serve the smart protocol to clients that look like git.
+	req.Header.Set("User-Agent", "git/2.0 (chainparse)")
+	client := &http.Client{Transport: fr.rt}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("git ls-remote %q failed with status: %q", repoURL, res.Status)
+	}
+	if ct := res.Header.Get("Content-Type"); ct != "application/x-git-upload-pack-advertisement" {
+		return nil, fmt.Errorf("%w: %q has Content-Type %q", errNotSmartHTTP, repoURL, ct)
+	}
+	refs, err = parseRefAdvertisement(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("git ls-remote %q: %w", repoURL, err)
+	}
+
+	fr.mu.Lock()
+	fr.refsCache[cacheKey] = refs
+	fr.mu.Unlock()
+	return refs, nil
+}
+
+// parseRefAdvertisement parses the pkt-line encoded reply to a smart HTTP
+// info/refs request as documented in gitprotocol-http(5), of the form:
+//
+//	001e# service=git-upload-pack\n
+//	0000
+//	00a3<oid> HEAD\0multi_ack ... symref=HEAD:refs/heads/main ...\n
+//	003f<oid> refs/heads/main\n
+//	003f<oid> refs/tags/v1.0.0\n
+//	0042<oid> refs/tags/v1.0.0^{}\n
+//	0000
+func parseRefAdvertisement(r io.Reader) (*gitRefs, error) {
+	br := bufio.NewReader(r)
+	refs := &gitRefs{
+		Refs:   make(map[string]string),
+		Peeled: make(map[string]string),
+	}
+
+	line, err := readPktLine(br)
+	if err != nil {
+		return nil, err
+	}
+	if line == nil || !bytes.HasPrefix(line, []byte("# service=")) {
+		return nil, fmt.Errorf("%w: missing the service announcement", errNotSmartHTTP)
+	}
+	// The service announcement is terminated by a flush-pkt.
+	if line, err = readPktLine(br); err != nil {
+		return nil, err
+	} else if line != nil {
+		return nil, fmt.Errorf("expected a flush-pkt after the service announcement, got %q", line)
+	}
+
+	first := true
+	for {
+		line, err := readPktLine(br)
+		if err != nil {
+			return nil, err
+		}
+		if line == nil { // flush-pkt: end of the advertisement.
+			return refs, nil
+		}
+		line = bytes.TrimSuffix(line, []byte("\n"))
+		if first {
+			first = false
+			var caps []byte
+			if i := bytes.IndexByte(line, 0); i >= 0 {
+				line, caps = line[:i], line[i+1:]
+			}
+			for _, capability := range strings.Fields(string(caps)) {
+				if target := strings.TrimPrefix(capability, "symref=HEAD:"); target != capability {
+					refs.Head = target
+				}
+			}
+		}
+
+		oid, name, ok := strings.Cut(string(line), " ")
+		if !ok {
+			return nil, fmt.Errorf("malformed ref line %q", line)
+		}
+		switch {
+		case name == "capabilities^{}":
+			// An empty repository advertises no refs, only its capabilities.
+		case strings.HasSuffix(name, "^{}"):
+			refs.Peeled[strings.TrimSuffix(name, "^{}")] = oid
+		default:
+			refs.Refs[name] = oid
+		}
+	}
+}
+
+// readPktLine reads a single pkt-line returning a nil payload for a flush-pkt.
+func readPktLine(br *bufio.Reader) ([]byte, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	n, err := strconv.ParseUint(string(hdr[:]), 16, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pkt-line length %q", hdr)
+	}
+	switch {
+	case n == 0:
+		return nil, nil
+	case n < 4:
+		return nil, fmt.Errorf("invalid pkt-line length %d", n)
+	}
+	payload := make([]byte, n-4)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
@@ -0,0 +1,175 @@
+package chainparse
+
+import (
+	"context"
+	"fmt"
+	"net/http/cgi"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// pktLines encodes lines as pkt-lines, with an empty line becoming a flush-pkt.
+func pktLines(lines ...string) string {
+	var sb strings.Builder
+	for _, line := range lines {
+		if line == "" {
+			sb.WriteString("0000")
+			continue
+		}
+		fmt.Fprintf(&sb, "%04x%s", len(line)+4, line)
+	}
+	return sb.String()
+}
+
+const (
+	oidMain = "1111111111111111111111111111111111111111"
+	oidTag  = "2222222222222222222222222222222222222222"
+	oidPeel = "3333333333333333333333333333333333333333"
+)
+
+// agoricAdvertisement is what a smart HTTP server advertises for a
+// repository whose HEAD points at refs/heads/Agoric.
+var agoricAdvertisement = pktLines(
+	"# service=git-upload-pack\n",
+	"",
+	oidMain+" HEAD\x00multi_ack thin-pack side-band side-band-64k ofs-delta shallow symref=HEAD:refs/heads/Agoric agent=git/github-g2\n",
+	oidMain+" refs/heads/Agoric\n",
+	oidMain+" refs/heads/feature/with-slashes\n",
+	oidMain+" refs/tags/agoric-3.1\n",
+	oidTag+" refs/tags/v0.27.0\n",
+	oidPeel+" refs/tags/v0.27.0^{}\n",
+	"",
+)
+
+func TestParseRefAdvertisement(t *testing.T) {
+	refs, err := parseRefAdvertisement(strings.NewReader(agoricAdvertisement))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &gitRefs{
+		Head: "refs/heads/Agoric",
+		Refs: map[string]string{
+			"HEAD":                            oidMain,
+			"refs/heads/Agoric":               oidMain,
+			"refs/heads/feature/with-slashes": oidMain,
+			"refs/tags/agoric-3.1":            oidMain,
+			"refs/tags/v0.27.0":               oidTag,
+		},
+		Peeled: map[string]string{
+			"refs/tags/v0.27.0": oidPeel,
+		},
+	}
+	if diff := cmp.Diff(refs, want); diff != "" {
+		t.Fatalf("Refs mismatch: got - want +\n%s", diff)
+	}
+	if g, w := refs.DefaultBranch(), "Agoric"; g != w {
+		t.Errorf("Default branch: got %q, want %q", g, w)
+	}
+	tags := refs.Tags()
+	sort.Strings(tags)
+	if diff := cmp.Diff(tags, []string{"agoric-3.1", "v0.27.0"}); diff != "" {
+		t.Errorf("Tags mismatch: got - want +\n%s", diff)
+	}
+}
+
+func TestParseRefAdvertisementErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"dumb server", "1111111111111111111111111111111111111111\trefs/heads/main\n"},
+		{"truncated", pktLines("# service=git-upload-pack\n", "", oidMain+" HEAD\x00symref=HEAD:refs/heads/main\n")},
+		{"malformed ref", pktLines("# service=git-upload-pack\n", "", "not-a-ref-line\n", "")},
+	}
+	for _, tt := range tests {
+		if refs, err := parseRefAdvertisement(strings.NewReader(tt.in)); err == nil {
+			t.Errorf("%s: expected an error, got %#v", tt.name, refs)
+		}
+	}
+
+	// An empty repository is valid but has no refs at all.
+	empty := pktLines(
+		"# service=git-upload-pack\n",
+		"",
+		"0000000000000000000000000000000000000000 capabilities^{}\x00multi_ack agent=git/2.40.0\n",
+		"",
+	)
+	refs, err := parseRefAdvertisement(strings.NewReader(empty))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refs.Head != "" || len(refs.Refs) != 0 {
+		t.Fatalf("Expected no refs for an empty repository, got %#v", refs)
+	}
+}
+
+// TestLsRemoteGitHTTPBackend checks interoperability with git's own
+// smart HTTP server, git-http-backend(1), serving a local repository.
+func TestLsRemoteGitHTTPBackend(t *testing.T) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+
+	root := t.TempDir()
+	work := filepath.Join(root, "work")
+	git := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command(gitPath, args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=chainparse", "GIT_AUTHOR_EMAIL=chainparse@example.com",
+			"GIT_COMMITTER_NAME=chainparse", "GIT_COMMITTER_EMAIL=chainparse@example.com",
+			"GIT_CONFIG_NOSYSTEM=1", "HOME="+root,
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	git(root, "init", "-q", work)
+	git(work, "checkout", "-q", "-b", "Agoric")
+	if err := os.WriteFile(filepath.Join(work, "go.mod"), testdataGoMod, 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(work, "add", "go.mod")
+	git(work, "commit", "-q", "-m", "initial")
+	git(work, "tag", "agoric-3.1")
+	git(work, "tag", "-a", "-m", "annotated", "v0.27.0")
+	git(root, "clone", "-q", "--bare", work, filepath.Join(root, "Agoric", "ag0.git"))
+
+	cst := httptest.NewServer(&cgi.Handler{
+		Path: gitPath,
+		Args: []string{"http-backend"},
+		Env:  []string{"GIT_PROJECT_ROOT=" + root, "GIT_HTTP_EXPORT_ALL=1"},
+	})
+	defer cst.Close()
+
+	destURL, err := url.Parse(cst.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fr := newFetcher(&alwaysToURLRoundTripper{next: cst.Client(), destURL: destURL})
+	refs, err := fr.lsRemote(context.Background(), RepoID{Host: "github.com", Owner: "Agoric", Repo: "ag0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, w := refs.DefaultBranch(), "Agoric"; g != w {
+		t.Errorf("Default branch: got %q, want %q", g, w)
+	}
+	tags := refs.Tags()
+	sort.Strings(tags)
+	if diff := cmp.Diff(tags, []string{"agoric-3.1", "v0.27.0"}); diff != "" {
+		t.Errorf("Tags mismatch: got - want +\n%s", diff)
+	}
+	if refs.Peeled["refs/tags/v0.27.0"] != refs.Refs["refs/heads/Agoric"] {
+		t.Errorf("The annotated tag should peel to the branch's commit, got %#v", refs)
+	}
+}
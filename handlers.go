@@ -1,8 +1,10 @@
 package chainparse
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/sirupsen/logrus"
@@ -13,31 +15,52 @@ type ChainParser struct {
 	fetcher *fetcher
 }
 
-func NewChainParser(rt http.RoundTripper) *ChainParser {
+func NewChainParser(rt http.RoundTripper, opts ...Option) *ChainParser {
 	if rt == nil {
 		rt = http.DefaultTransport
 	}
 	return &ChainParser{
-		fetcher: newFetcher(rt),
+		fetcher: newFetcher(rt, opts...),
 	}
 }
 
-func RetrieveChainData(ctx context.Context, rt http.RoundTripper) ([]*ChainSchema, error) {
-	fetcher := &fetcher{rt: rt}
+func RetrieveChainData(ctx context.Context, rt http.RoundTripper, opts ...Option) ([]*ChainSchema, error) {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	fetcher := newFetcher(rt, opts...)
 	return fetcher.fetchChainData(ctx)
 }
 
+// RetrieveResult is like RetrieveChainData but also reports the Outcome of
+// every chain in the registry, including those that were skipped or failed.
+func RetrieveResult(ctx context.Context, rt http.RoundTripper, opts ...Option) (*Result, error) {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	fetcher := newFetcher(rt, opts...)
+	return fetcher.fetchResult(ctx)
+}
+
+// FetchData serves the chains of the last snapshot taken keyed by their
+// pretty name, see Refresh. Responses are rendered once per snapshot, carry
+// a strong ETag and are compressed as negotiated, see snapshotResponse.
 func (cp *ChainParser) FetchData(rw http.ResponseWriter, req *http.Request) {
 	ctx, span := trace.StartSpan(req.Context(), "FetchData")
 	defer span.End()
 
 	// 1. Fetch the various values.
-	chainSchemaL, err := cp.fetcher.fetchChainData(ctx)
+	snap, err := cp.fetcher.snapshot(ctx)
 	if err != nil {
 		logrus.WithContext(ctx).WithError(err).Error("failed to retrieve all chain schema")
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if resp := snap.cachedResponse("data"); resp != nil {
+		resp.serve(rw, req, snap)
+		return
+	}
+	chainSchemaL := snap.res.Chains
 
 	// 2. Normalize the schema data for quick lookups by key: O(n) -> O(1)
 	byPrettyName := make(map[string]*ChainSchema, len(chainSchemaL))
@@ -45,10 +68,91 @@ func (cp *ChainParser) FetchData(rw http.ResponseWriter, req *http.Request) {
 		byPrettyName[cs.PrettyName] = cs
 	}
 
-	enc := json.NewEncoder(rw)
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
 	if err := enc.Encode(byPrettyName); err != nil {
-		logrus.WithContext(ctx).WithError(err).Error("failed to JSON marshal & send the retrieved chain info")
+		logrus.WithContext(ctx).WithError(err).Error("failed to JSON marshal the retrieved chain info")
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	snap.cacheResponse("data", rw.Header(), body.Bytes()).serve(rw, req, snap)
+}
+
+// FetchReport serves the chains along with the Outcome of every chain in the registry,
+// as JSON unless the format query parameter asks for "markdown", "html", "xlsx",
+// or for the fork lineage as "dot" or "mermaid",
+// or the template query parameter names a template passed to WithTemplates.
+// Like FetchData, it serves the last snapshot taken, see Refresh, rendered
+// once per snapshot with an ETag and compression, see snapshotResponse.
+func (cp *ChainParser) FetchReport(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := trace.StartSpan(req.Context(), "FetchReport")
+	defer span.End()
+
+	format := req.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	key := "report?format=" + format
+	var tmpl *Template
+	if name := req.URL.Query().Get("template"); name != "" {
+		if tmpl = cp.fetcher.templates[name]; tmpl == nil {
+			http.Error(rw, fmt.Sprintf("unknown template %q", name), http.StatusNotFound)
+			return
+		}
+		key = "report?template=" + name
+	}
+
+	snap, err := cp.fetcher.snapshot(ctx)
+	if err != nil {
+		logrus.WithContext(ctx).WithError(err).Error("failed to retrieve all chain schema")
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if resp := snap.cachedResponse(key); resp != nil {
+		resp.serve(rw, req, snap)
+		return
+	}
+	res := snap.res
+
+	// The snapshot's time stands for the generation time so that the same
+	// snapshot always gets the same ETag.
+	var body bytes.Buffer
+	switch {
+	case tmpl != nil:
+		if tmpl.IsHTML() {
+			rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		} else {
+			rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		}
+		err = tmpl.Execute(&body, res, snap.at)
+	case format == "json":
+		rw.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(&body).Encode(res)
+	case format == "markdown":
+		rw.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		err = WriteMarkdownReport(&body, res, snap.at)
+	case format == "html":
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err = WriteHTMLReport(&body, res, snap.at)
+	case format == "xlsx":
+		rw.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		rw.Header().Set("Content-Disposition", `attachment; filename="chainparse.xlsx"`)
+		err = WriteXLSX(&body, res)
+	case format == "dot":
+		rw.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		err = WriteDOT(&body, NewLineage(res))
+	case format == "mermaid":
+		rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		err = WriteMermaid(&body, NewLineage(res))
+	default:
+		http.Error(rw, fmt.Sprintf("unknown format %q, expected json, markdown, html, xlsx, dot or mermaid", format), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logrus.WithContext(ctx).WithError(err).Error("failed to render the retrieved report")
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	snap.cacheResponse(key, rw.Header(), body.Bytes()).serve(rw, req, snap)
 }
@@ -0,0 +1,209 @@
+package chainparse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// LatestStrategy selects the git ref whose go.mod is analysed
+// to populate ChainSchema.Latest, next to the registry's
+// recommended_version which is always analysed.
+type LatestStrategy string
+
+const (
+	// LatestDisabled skips the latest analysis altogether.
+	LatestDisabled LatestStrategy = ""
+
+	// LatestDefaultBranch analyses the tip of the repository's default branch.
+	LatestDefaultBranch LatestStrategy = "default-branch"
+
+	// LatestSemverTag analyses the newest stable semver tag,
+	// or the newest pre-release if there are no stable tags.
+	LatestSemverTag LatestStrategy = "semver-tag"
+
+	// LatestGitHubRelease analyses the tag of the newest GitHub release.
+	LatestGitHubRelease LatestStrategy = "release"
+)
+
+// LatestStrategies lists all the strategies that enable the latest analysis.
+var LatestStrategies = []LatestStrategy{LatestDefaultBranch, LatestSemverTag, LatestGitHubRelease}
+
+// ParseLatestStrategy parses the name of a LatestStrategy, "" and "none" disable the analysis.
+func ParseLatestStrategy(name string) (LatestStrategy, error) {
+	if name == "" || name == "none" {
+		return LatestDisabled, nil
+	}
+	for _, strategy := range LatestStrategies {
+		if LatestStrategy(name) == strategy {
+			return strategy, nil
+		}
+	}
+	return "", fmt.Errorf("unknown latest strategy %q, expected one of %q", name, LatestStrategies)
+}
+
+// DefaultLatestConcurrency is how many chains can be undergoing the latest
+// analysis at once unless set with WithLatestConcurrency.
+const DefaultLatestConcurrency = 4
+
+// WithLatest enables the latest analysis using strategy.
+func WithLatest(strategy LatestStrategy) Option {
+	return func(fr *fetcher) {
+		fr.latest = strategy
+	}
+}
+
+// WithLatestConcurrency bounds how many chains can be undergoing the
+// latest analysis at once, independently of the registry's own fetches.
+func WithLatestConcurrency(n int) Option {
+	return func(fr *fetcher) {
+		if n > 0 {
+			fr.latestSem = make(chan struct{}, n)
+		}
+	}
+}
+
+var errNoLatestRef = errors.New("no ref matches the latest strategy")
+
+// latestRef returns the git ref to analyse for the configured latest strategy.
+func (fr *fetcher) latestRef(ctx context.Context, repo RepoID) (string, error) {
+	switch fr.latest {
+	case LatestDefaultBranch:
+		return fr.defaultBranchForRepo(ctx, repo)
+
+	case LatestSemverTag:
+		refs, err := fr.lsRemote(ctx, repo)
+		if err != nil {
+			return "", err
+		}
+		if tag := newestSemver(refs.Tags(), true); tag != "" {
+			return tag, nil
+		}
+		if tag := newestSemver(refs.Tags(), false); tag != "" {
+			return tag, nil
+		}
+		return "", errNoLatestRef
+
+	case LatestGitHubRelease:
+		return fr.latestGitHubRelease(ctx, repo)
+
+	default:
+		return "", fmt.Errorf("unknown latest strategy %q", fr.latest)
+	}
+}
+
+// newestSemver returns the newest of tags when interpreted as semantic versions,
+// tags without the "v" prefix such as "1.2.0" are treated as "v1.2.0". If stable
+// is set, then pre-releases are excluded. It returns "" if no tag is a version.
+func newestSemver(tags []string, stable bool) string {
+	var newest, newestVers string
+	for _, tag := range tags {
+		vers := tagSemver(tag)
+		if vers == "" || (stable && semver.Prerelease(vers) != "") {
+			continue
+		}
+		if newestVers == "" || semver.Compare(vers, newestVers) > 0 {
+			newest, newestVers = tag, vers
+		}
+	}
+	return newest
+}
+
+// tagSemver returns the canonical semantic version for tag or "" if it isn't one.
+func tagSemver(tag string) string {
+	vers := tag
+	if !strings.HasPrefix(vers, "v") {
+		vers = "v" + vers
+	}
+	if !semver.IsValid(vers) {
+		return ""
+	}
+	return semver.Canonical(vers)
+}
+
+func (fr *fetcher) latestGitHubRelease(ctx context.Context, repo RepoID) (string, error) {
+	if ghRepo := fr.cachedGitHubRepo(repo); ghRepo != nil && ghRepo.LatestRelease != "" {
+		return ghRepo.LatestRelease, nil
+	}
+
+	if !repo.IsGitHub() {
+		return "", fmt.Errorf("not a GitHub repository: %q", repo)
+	}
+	owner, name := repo.Owner, repo.Repo
+	apiURL := githubAPIURL + path.Join("/repos", owner, name, "releases", "latest")
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if fr.githubToken != "" {
+		req.Header.Set("Authorization", "Bearer "+fr.githubToken)
+	}
+	client := &http.Client{Transport: fr.rt}
+	res, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	blob, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return "", errNoLatestRef
+	case res.StatusCode < 200 || res.StatusCode > 299:
+		return "", fmt.Errorf("GitHub latest release request failed with status: %q", res.Status)
+	}
+	release := new(struct {
+		TagName string `json:"tag_name"`
+	})
+	if err := json.Unmarshal(blob, release); err != nil {
+		return "", err
+	}
+
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	ghRepo := fr.githubRepoEntry(repo)
+	ghRepo.LatestRelease = release.TagName
+	return release.TagName, nil
+}
+
+// retrieveLatest analyses the go.mod at the ref chosen by the latest strategy.
+func (fr *fetcher) retrieveLatest(ctx context.Context, client *http.Client, repo RepoID, seedCS ChainSchema) (cs *ChainSchema, uri string, err error) {
+	// The latest analysis has its own concurrency limit so that enabling
+	// it doesn't starve nor double the load of the registry's fetches.
+	select {
+	case fr.latestSem <- struct{}{}:
+		defer func() { <-fr.latestSem }()
+	case <-ctx.Done():
+		return nil, "", ctx.Err()
+	}
+
+	ref, err := fr.latestRef(ctx, repo)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Prefer the go.mod files from the batched GitHub lookups, the default
+	// branch's go.mod having been looked up as the one at HEAD.
+	modBlob, ok := fr.cachedGoMod(repo, ref)
+	if !ok && fr.latest == LatestDefaultBranch {
+		modBlob, ok = fr.cachedGoMod(repo, "HEAD")
+	}
+	if ok {
+		cs, err = fr.parseModFile(modBlob, seedCS)
+		return cs, "", err
+	}
+
+	uri = rawGoModURL(repo, ref)
+	cs, err = fr.retrieveModFile(ctx, client, modCacheKey(repo, ref), uri, seedCS)
+	return cs, uri, err
+}
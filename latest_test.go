@@ -0,0 +1,106 @@
+package chainparse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestNewestSemver(t *testing.T) {
+	tags := []string{"agoric-3.1", "v0.9.0", "1.10.0", "v1.9.3", "v2.0.0-rc.1", "v2.0.0-beta.2", "nightly"}
+	if g, w := newestSemver(tags, true), "1.10.0"; g != w {
+		t.Errorf("Newest stable: got %q, want %q", g, w)
+	}
+	if g, w := newestSemver(tags, false), "v2.0.0-rc.1"; g != w {
+		t.Errorf("Newest including pre-releases: got %q, want %q", g, w)
+	}
+	if g := newestSemver([]string{"main", "agoric-3.1"}, false); g != "" {
+		t.Errorf("Expected no version, got %q", g)
+	}
+}
+
+func TestParseLatestStrategy(t *testing.T) {
+	for _, name := range []string{"", "none"} {
+		if strategy, err := ParseLatestStrategy(name); err != nil || strategy != LatestDisabled {
+			t.Errorf("%q: got (%q, %v), want disabled", name, strategy, err)
+		}
+	}
+	for _, want := range LatestStrategies {
+		if strategy, err := ParseLatestStrategy(string(want)); err != nil || strategy != want {
+			t.Errorf("%q: got (%q, %v)", want, strategy, err)
+		}
+	}
+	if _, err := ParseLatestStrategy("newest"); err == nil {
+		t.Error("Expected an error for an unknown strategy")
+	}
+}
+
+func TestRetrieveLatestStrategies(t *testing.T) {
+	var goModRequests int32
+	cst := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch p := req.URL.Path; {
+		case p == "/Agoric/ag0/info/refs":
+			rw.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
+			rw.Write([]byte(agoricAdvertisement))
+		case p == "/repos/Agoric/ag0/releases/latest":
+			rw.Write([]byte(`{"tag_name": "agoric-upgrade-8", "name": "Upgrade 8"}`))
+		case p == "/Agoric/ag0/v0.27.0/go.mod", p == "/Agoric/ag0/agoric-upgrade-8/go.mod":
+			atomic.AddInt32(&goModRequests, 1)
+			rw.Write(testdataLatestGoMod)
+		case strings.HasSuffix(p, "/go.mod"):
+			atomic.AddInt32(&goModRequests, 1)
+			rw.Write(testdataGoMod)
+		default:
+			http.NotFound(rw, req)
+		}
+	}))
+	defer cst.Close()
+
+	destURL, err := url.Parse(cst.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	art := &alwaysToURLRoundTripper{next: cst.Client(), destURL: destURL}
+	seed := ChainSchema{
+		ChainName:   "agoric",
+		NetworkType: "mainnet",
+		Codebase:    &Codebase{GitRepoURL: "https://github.com/Agoric/ag0/", RecommendedVersion: "agoric-3.1"},
+	}
+
+	tests := []struct {
+		strategy LatestStrategy
+		wantTM   string
+		wantURL  string
+	}{
+		{LatestDefaultBranch, "v0.34.13@github.com/tendermint/tendermint", "https://raw.githubusercontent.com/Agoric/ag0/Agoric/go.mod"},
+		{LatestSemverTag, "v0.37.13@github.com/tendermint/tendermint", "https://raw.githubusercontent.com/Agoric/ag0/v0.27.0/go.mod"},
+		{LatestGitHubRelease, "v0.37.13@github.com/tendermint/tendermint", "https://raw.githubusercontent.com/Agoric/ag0/agoric-upgrade-8/go.mod"},
+	}
+
+	ctx := context.Background()
+	client := &http.Client{Transport: art}
+	for _, tt := range tests {
+		atomic.StoreInt32(&goModRequests, 0)
+		fr := newFetcher(art, WithLatest(tt.strategy), WithLatestConcurrency(1))
+		// Looking up the same repository twice must only download its go.mod once.
+		for i := 0; i < 2; i++ {
+			cs, uri, err := fr.retrieveLatest(ctx, client, RepoID{Host: "github.com", Owner: "Agoric", Repo: "ag0"}, seed)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.strategy, err)
+			}
+			if g, w := uri, tt.wantURL; g != w {
+				t.Errorf("%s: URL mismatch: got %q, want %q", tt.strategy, g, w)
+			}
+			if g, w := cs.TendermintVersion, tt.wantTM; g != w {
+				t.Errorf("%s: Tendermint version mismatch: got %q, want %q", tt.strategy, g, w)
+			}
+		}
+		if g, w := atomic.LoadInt32(&goModRequests), int32(1); g != w {
+			t.Errorf("%s: go.mod requests: got %d, want %d", tt.strategy, g, w)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package chainparse
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// lineageUpstreams are the upstream module paths of the tracked dependencies
+// that a fork is drawn against when the replaced module isn't known.
+var lineageUpstreams = map[string]string{
+	moduleCosmosSDK:  "github.com/cosmos/cosmos-sdk",
+	moduleTendermint: "github.com/tendermint/tendermint",
+	moduleIBC:        "github.com/cosmos/ibc-go",
+}
+
+// Lineage is the fork tree of the tracked dependencies: which chains depend
+// on which forks of the Cosmos SDK, Tendermint/CometBFT and IBC, and what
+// upstream version each fork is based on.
+type Lineage struct {
+	Forks []*Fork
+}
+
+// Fork is a version of a fork of one of the tracked dependencies.
+type Fork struct {
+	// Module is one of cosmos-sdk, tendermint or ibc-go.
+	Module  string
+	Path    string
+	Version string
+
+	// UpstreamPath and UpstreamVersion are the upstream module that the fork
+	// replaces. UpstreamVersion is the version required by the chains if their
+	// go.mod was retained with WithGoModules, or else the release line of the
+	// fork's version, such as v0.44, and empty if the fork's version isn't semver.
+	UpstreamPath    string
+	UpstreamVersion string
+
+	// Chains are the names of the chains depending on the fork, sorted.
+	Chains []string
+}
+
+// NewLineage returns the forks of the tracked dependencies amongst the chains
+// of res, sorted by module, path and version.
+func NewLineage(res *Result) *Lineage {
+	byKey := make(map[string]*Fork)
+	lin := new(Lineage)
+	for _, cs := range res.Chains {
+		deps := map[string]string{
+			moduleCosmosSDK:  cs.CosmosSDKVersion,
+			moduleTendermint: cs.TendermintVersion,
+			moduleIBC:        cs.IBCVersion,
+		}
+		for module, dep := range deps {
+			if !isForkedDependency(dep) {
+				continue
+			}
+			version, path := splitDependency(dep)
+			key := module + " " + path + "@" + version
+			fork := byKey[key]
+			if fork == nil {
+				fork = &Fork{Module: module, Path: path, Version: version}
+				byKey[key] = fork
+				lin.Forks = append(lin.Forks, fork)
+			}
+			fork.Chains = append(fork.Chains, cs.ChainName)
+			if fork.UpstreamPath == "" {
+				fork.UpstreamPath, fork.UpstreamVersion = forkUpstream(cs.GoModule, module, path, version)
+			}
+		}
+	}
+
+	moduleOrder := map[string]int{moduleCosmosSDK: 0, moduleTendermint: 1, moduleIBC: 2}
+	sort.Slice(lin.Forks, func(i, j int) bool {
+		fi, fj := lin.Forks[i], lin.Forks[j]
+		if fi.Module != fj.Module {
+			return moduleOrder[fi.Module] < moduleOrder[fj.Module]
+		}
+		if fi.Path != fj.Path {
+			return fi.Path < fj.Path
+		}
+		return fi.Version < fj.Version
+	})
+	for _, fork := range lin.Forks {
+		sort.Strings(fork.Chains)
+	}
+	return lin
+}
+
+// forkUpstream returns the upstream module replaced by the fork path@version,
+// looked up in gm if retained.
+func forkUpstream(gm *GoModule, module, path, version string) (upstreamPath, upstreamVersion string) {
+	if gm != nil {
+		for _, rep := range gm.Replaces {
+			if rep.NewPath != path || rep.NewVersion != version {
+				continue
+			}
+			if rep.OldVersion != "" {
+				return rep.OldPath, rep.OldVersion
+			}
+			for _, req := range gm.Requires {
+				if req.Path == rep.OldPath {
+					return rep.OldPath, req.Version
+				}
+			}
+			return rep.OldPath, ""
+		}
+	}
+
+	upstreamPath = lineageUpstreams[module]
+	if module == moduleTendermint && strings.Contains(path, "cometbft") {
+		upstreamPath = "github.com/cometbft/cometbft"
+	}
+	// Keep any major version suffix such as the /v7 of ibc-go.
+	if i := strings.LastIndexByte(path, '/'); i >= 0 && semver.IsValid(path[i+1:]) {
+		upstreamPath += path[i:]
+	}
+	return upstreamPath, semver.MajorMinor(version)
+}
+
+// lineageNode is a node of the graph drawn by WriteDOT and WriteMermaid.
+type lineageNode struct {
+	id, label string
+}
+
+// lineageGroup is a cluster of nodes, either the versions of a fork or of
+// an upstream module, or else the chains.
+type lineageGroup struct {
+	label  string
+	fork   bool
+	chains bool
+	nodes  []*lineageNode
+}
+
+type lineageEdge struct {
+	from, to, label string
+}
+
+// graph lays out the lineage: the versions of every fork are grouped by
+// fork module, each pointing at its upstream base version, with every chain
+// pointing at the forks it depends on. Chains sharing a fork thereby point
+// at the same node.
+func (lin *Lineage) graph() (groups []*lineageGroup, edges []*lineageEdge) {
+	groupsByLabel := make(map[string]*lineageGroup)
+	nodes := make(map[string]*lineageNode)
+	node := func(key, label string, group *lineageGroup) *lineageNode {
+		if n := nodes[key]; n != nil {
+			return n
+		}
+		n := &lineageNode{id: "n" + strconv.Itoa(len(nodes)), label: label}
+		nodes[key] = n
+		group.nodes = append(group.nodes, n)
+		return n
+	}
+	forkGroup := func(label string) *lineageGroup {
+		if g := groupsByLabel[label]; g != nil {
+			return g
+		}
+		g := &lineageGroup{label: label, fork: true}
+		groupsByLabel[label] = g
+		groups = append(groups, g)
+		return g
+	}
+
+	var upstreams []*lineageGroup
+	chains := &lineageGroup{label: "chains", chains: true}
+	for _, fork := range lin.Forks {
+		fn := node("fork "+fork.Path+"@"+fork.Version, fork.Version, forkGroup(fork.Path))
+		if fork.UpstreamPath != "" {
+			ug := groupsByLabel[fork.UpstreamPath]
+			if ug == nil {
+				ug = &lineageGroup{label: fork.UpstreamPath}
+				groupsByLabel[fork.UpstreamPath] = ug
+				upstreams = append(upstreams, ug)
+			}
+			label := fork.UpstreamVersion
+			if label == "" {
+				label = "unknown version"
+			}
+			un := node("upstream "+fork.UpstreamPath+"@"+fork.UpstreamVersion, label, ug)
+			edges = append(edges, &lineageEdge{from: fn.id, to: un.id, label: "fork of"})
+		}
+		for _, chain := range fork.Chains {
+			cn := node("chain "+chain, chain, chains)
+			edges = append(edges, &lineageEdge{from: cn.id, to: fn.id})
+		}
+	}
+	groups = append(groups, upstreams...)
+	if len(chains.nodes) != 0 {
+		groups = append(groups, chains)
+	}
+	return groups, edges
+}
+
+// dotQuote quotes s as a DOT string.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// WriteDOT writes the lineage as a Graphviz DOT digraph, with a cluster per
+// fork module and per upstream module.
+func WriteDOT(w io.Writer, lin *Lineage) error {
+	bw := bufio.NewWriter(w)
+	groups, edges := lin.graph()
+	fmt.Fprintln(bw, "digraph lineage {")
+	fmt.Fprintln(bw, "  rankdir=LR;")
+	fmt.Fprintln(bw, "  node [shape=box];")
+	for i, g := range groups {
+		indent := "  "
+		style := ""
+		switch {
+		case g.chains:
+			style = " shape=ellipse"
+		case g.fork:
+			style = ` style=filled fillcolor="#fde2c8"`
+		}
+		if !g.chains {
+			fmt.Fprintf(bw, "  subgraph cluster_%d {\n", i)
+			fmt.Fprintf(bw, "    label=%s;\n", dotQuote(g.label))
+			indent = "    "
+		}
+		for _, n := range g.nodes {
+			fmt.Fprintf(bw, "%s%s [label=%s%s];\n", indent, n.id, dotQuote(n.label), style)
+		}
+		if !g.chains {
+			fmt.Fprintln(bw, "  }")
+		}
+	}
+	for _, e := range edges {
+		if e.label != "" {
+			fmt.Fprintf(bw, "  %s -> %s [label=%s];\n", e.from, e.to, dotQuote(e.label))
+		} else {
+			fmt.Fprintf(bw, "  %s -> %s;\n", e.from, e.to)
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// mermaidQuote quotes s as a Mermaid label, which can't contain double quotes.
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
+
+// WriteMermaid writes the lineage as a Mermaid flowchart, with a subgraph
+// per fork module and per upstream module.
+func WriteMermaid(w io.Writer, lin *Lineage) error {
+	bw := bufio.NewWriter(w)
+	groups, edges := lin.graph()
+	fmt.Fprintln(bw, "flowchart LR")
+	for i, g := range groups {
+		indent := "  "
+		if !g.chains {
+			fmt.Fprintf(bw, "  subgraph g%d[%s]\n", i, mermaidQuote(g.label))
+			indent = "    "
+		}
+		for _, n := range g.nodes {
+			if g.chains {
+				fmt.Fprintf(bw, "%s%s([%s])\n", indent, n.id, mermaidQuote(n.label))
+			} else {
+				fmt.Fprintf(bw, "%s%s[%s]\n", indent, n.id, mermaidQuote(n.label))
+			}
+		}
+		if !g.chains {
+			fmt.Fprintln(bw, "  end")
+		}
+	}
+	for _, e := range edges {
+		if e.label != "" {
+			fmt.Fprintf(bw, "  %s -->|%s| %s\n", e.from, e.label, e.to)
+		} else {
+			fmt.Fprintf(bw, "  %s --> %s\n", e.from, e.to)
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,158 @@
+package chainparse
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/mod/modfile"
+)
+
+func lineageResult(t *testing.T) *Result {
+	modF, err := modfile.Parse("go.mod", testdataGoMod, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agoric := *reportResult.Chains[0]
+	agoric.GoModule = newGoModule(modF)
+	res := &Result{Chains: []*ChainSchema{&agoric, reportResult.Chains[1], reportResult.Chains[2]}}
+	// Another chain sharing Agoric's fork, and one on a CometBFT fork.
+	res.Chains = append(res.Chains,
+		&ChainSchema{ChainName: "agoric-devnet", CosmosSDKVersion: agoric.CosmosSDKVersion},
+		&ChainSchema{ChainName: "neutron", TendermintVersion: "v0.37.1-sdk47@github.com/neutron-org/cometbft", IBCVersion: "v7.1.0@github.com/strangelove-ventures/ibc-go/v7"},
+	)
+	return res
+}
+
+func TestNewLineage(t *testing.T) {
+	got := NewLineage(lineageResult(t))
+	want := &Lineage{Forks: []*Fork{
+		{
+			Module:          moduleCosmosSDK,
+			Path:            "github.com/agoric-labs/cosmos-sdk",
+			Version:         "v0.44.2-alpha.agoric.gaiad.1",
+			UpstreamPath:    "github.com/cosmos/cosmos-sdk",
+			UpstreamVersion: "v0.44.1",
+			Chains:          []string{"agoric", "agoric-devnet"},
+		},
+		{
+			Module:          moduleTendermint,
+			Path:            "github.com/neutron-org/cometbft",
+			Version:         "v0.37.1-sdk47",
+			UpstreamPath:    "github.com/cometbft/cometbft",
+			UpstreamVersion: "v0.37",
+			Chains:          []string{"neutron"},
+		},
+		{
+			Module:          moduleIBC,
+			Path:            "github.com/strangelove-ventures/ibc-go/v7",
+			Version:         "v7.1.0",
+			UpstreamPath:    "github.com/cosmos/ibc-go/v7",
+			UpstreamVersion: "v7.1",
+			Chains:          []string{"neutron"},
+		},
+	}}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Lineage mismatch: got - want +\n%s", diff)
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	var b strings.Builder
+	if err := WriteDOT(&b, NewLineage(lineageResult(t))); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph lineage {
+  rankdir=LR;
+  node [shape=box];
+  subgraph cluster_0 {
+    label="github.com/agoric-labs/cosmos-sdk";
+    n0 [label="v0.44.2-alpha.agoric.gaiad.1" style=filled fillcolor="#fde2c8"];
+  }
+  subgraph cluster_1 {
+    label="github.com/neutron-org/cometbft";
+    n4 [label="v0.37.1-sdk47" style=filled fillcolor="#fde2c8"];
+  }
+  subgraph cluster_2 {
+    label="github.com/strangelove-ventures/ibc-go/v7";
+    n7 [label="v7.1.0" style=filled fillcolor="#fde2c8"];
+  }
+  subgraph cluster_3 {
+    label="github.com/cosmos/cosmos-sdk";
+    n1 [label="v0.44.1"];
+  }
+  subgraph cluster_4 {
+    label="github.com/cometbft/cometbft";
+    n5 [label="v0.37"];
+  }
+  subgraph cluster_5 {
+    label="github.com/cosmos/ibc-go/v7";
+    n8 [label="v7.1"];
+  }
+  n2 [label="agoric" shape=ellipse];
+  n3 [label="agoric-devnet" shape=ellipse];
+  n6 [label="neutron" shape=ellipse];
+  n0 -> n1 [label="fork of"];
+  n2 -> n0;
+  n3 -> n0;
+  n4 -> n5 [label="fork of"];
+  n6 -> n4;
+  n7 -> n8 [label="fork of"];
+  n6 -> n7;
+}
+`
+	if diff := cmp.Diff(b.String(), want); diff != "" {
+		t.Errorf("DOT mismatch: got - want +\n%s", diff)
+	}
+}
+
+func TestWriteMermaid(t *testing.T) {
+	var b strings.Builder
+	if err := WriteMermaid(&b, NewLineage(lineageResult(t))); err != nil {
+		t.Fatal(err)
+	}
+	got := b.String()
+	for _, want := range []string{
+		"flowchart LR\n",
+		"  subgraph g0[\"github.com/agoric-labs/cosmos-sdk\"]\n    n0[\"v0.44.2-alpha.agoric.gaiad.1\"]\n  end\n",
+		"  n0 -->|fork of| n1\n",
+		"  n6([\"neutron\"])\n",
+		"  n6 --> n7\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Mermaid output lacks %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestNewLineageCometBFT(t *testing.T) {
+	chain := func(name, goMod string) *ChainSchema {
+		modF, err := modfile.Parse("go.mod", []byte(goMod), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cs := &ChainSchema{ChainName: name, GoModule: newGoModule(modF)}
+		cs.CosmosSDKVersion, cs.TendermintVersion, cs.IBCVersion = extractCosmosTuples(modF)
+		return cs
+	}
+	res := &Result{Chains: []*ChainSchema{
+		// Replacing Tendermint with its upstream successor isn't a fork.
+		chain("osmosis", `module example.com/osmosis
+require github.com/tendermint/tendermint v0.34.27
+replace github.com/tendermint/tendermint => github.com/cometbft/cometbft v0.34.29`),
+		chain("skip", `module example.com/skip
+require github.com/cometbft/cometbft v0.37.2
+replace github.com/cometbft/cometbft => github.com/skip-mev/cometbft v0.37.2-skip.1`),
+	}}
+	want := &Lineage{Forks: []*Fork{{
+		Module:          moduleTendermint,
+		Path:            "github.com/skip-mev/cometbft",
+		Version:         "v0.37.2-skip.1",
+		UpstreamPath:    "github.com/cometbft/cometbft",
+		UpstreamVersion: "v0.37.2",
+		Chains:          []string{"skip"},
+	}}}
+	if diff := cmp.Diff(NewLineage(res), want); diff != "" {
+		t.Errorf("Lineage mismatch: got - want +\n%s", diff)
+	}
+}
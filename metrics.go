@@ -0,0 +1,260 @@
+package chainparse
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fetchMetrics accumulates what the /metrics endpoint exposes about the
+// refreshes of a fetcher.
+type fetchMetrics struct {
+	mu sync.Mutex
+
+	// last is the Result of the last successful refresh.
+	last         *Result
+	lastRefresh  time.Time
+	lastDuration time.Duration
+
+	refreshes map[string]int // by "success" or "failure"
+	outcomes  map[outcomeKey]int
+
+	// rateLimit is GitHub's X-RateLimit-Remaining by X-RateLimit-Resource.
+	rateLimit map[string]int
+}
+
+type outcomeKey struct {
+	status   OutcomeStatus
+	category ErrorCategory
+}
+
+func newFetchMetrics() *fetchMetrics {
+	return &fetchMetrics{
+		refreshes: map[string]int{"success": 0, "failure": 0},
+		outcomes:  make(map[outcomeKey]int),
+		rateLimit: make(map[string]int),
+	}
+}
+
+// recordRefresh records a refresh that finished at finished after took.
+func (m *fetchMetrics) recordRefresh(res *Result, finished time.Time, took time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err != nil {
+		m.refreshes["failure"]++
+		return
+	}
+	m.refreshes["success"]++
+	m.last = res
+	m.lastRefresh = finished
+	m.lastDuration = took
+	for _, out := range res.Outcomes {
+		m.outcomes[outcomeKey{out.Status, out.Category}]++
+	}
+}
+
+// rateLimitTransport records the rate limit left by every GitHub API response.
+type rateLimitTransport struct {
+	next    http.RoundTripper
+	metrics *fetchMetrics
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Check the host first as the next RoundTripper may rewrite it.
+	github := req.URL.Host == "api.github.com"
+	res, err := t.next.RoundTrip(req)
+	if err != nil || !github {
+		return res, err
+	}
+	remaining, perr := strconv.Atoi(res.Header.Get("X-RateLimit-Remaining"))
+	if perr != nil {
+		return res, err
+	}
+	resource := res.Header.Get("X-RateLimit-Resource")
+	if resource == "" {
+		resource = "core"
+	}
+	t.metrics.mu.Lock()
+	t.metrics.rateLimit[resource] = remaining
+	t.metrics.mu.Unlock()
+	return res, err
+}
+
+// metricFamily is a metric along with its samples in the exposition formats.
+type metricFamily struct {
+	name string
+	typ  string // "info", "counter" or "gauge"
+	help string
+
+	samples []metricSample
+}
+
+type metricSample struct {
+	labels [][2]string
+	value  float64
+}
+
+// write writes the metrics in the OpenMetrics text format if openMetrics is
+// set, else in the Prometheus text format 0.0.4.
+func (m *fetchMetrics) write(w io.Writer, openMetrics bool) error {
+	m.mu.Lock()
+	families := m.families()
+	m.mu.Unlock()
+
+	var b strings.Builder
+	for _, mf := range families {
+		// OpenMetrics names families without the suffix of their samples.
+		name, typ, suffix := mf.name, mf.typ, ""
+		switch mf.typ {
+		case "info":
+			suffix = "_info"
+			if !openMetrics {
+				name, typ = name+suffix, "gauge"
+			}
+		case "counter":
+			suffix = "_total"
+			if !openMetrics {
+				name += suffix
+			}
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, mf.help, name, typ)
+		for _, s := range mf.samples {
+			b.WriteString(mf.name + suffix)
+			if len(s.labels) != 0 {
+				b.WriteByte('{')
+				for i, l := range s.labels {
+					if i != 0 {
+						b.WriteByte(',')
+					}
+					fmt.Fprintf(&b, "%s=\"%s\"", l[0], metricLabelEscaper.Replace(l[1]))
+				}
+				b.WriteByte('}')
+			}
+			fmt.Fprintf(&b, " %s\n", strconv.FormatFloat(s.value, 'g', -1, 64))
+		}
+	}
+	if openMetrics {
+		b.WriteString("# EOF\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// families returns the metric families, m.mu must be held.
+func (m *fetchMetrics) families() []*metricFamily {
+	moduleInfo := &metricFamily{
+		name: "chainparse_chain_module",
+		typ:  "info",
+		help: "The version of each tracked module that a chain's recommended version is built on.",
+	}
+	if m.last != nil {
+		for _, cs := range m.last.Chains {
+			for _, mod := range []struct{ name, dep string }{
+				{moduleCosmosSDK, cs.CosmosSDKVersion},
+				{moduleTendermint, cs.TendermintVersion},
+				{moduleIBC, cs.IBCVersion},
+			} {
+				if mod.dep == "" {
+					continue
+				}
+				vers, replacedBy := splitDependency(mod.dep)
+				moduleInfo.samples = append(moduleInfo.samples, metricSample{
+					labels: [][2]string{{"chain", cs.ChainName}, {"module", mod.name}, {"version", vers}, {"replaced", replacedBy}},
+					value:  1,
+				})
+			}
+		}
+	}
+
+	outcomes := &metricFamily{
+		name: "chainparse_fetch_outcomes",
+		typ:  "counter",
+		help: "The outcomes of the chains analysed by every refresh, by status and error category.",
+	}
+	keys := make([]outcomeKey, 0, len(m.outcomes))
+	for key := range m.outcomes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].status != keys[j].status {
+			return keys[i].status < keys[j].status
+		}
+		return keys[i].category < keys[j].category
+	})
+	for _, key := range keys {
+		outcomes.samples = append(outcomes.samples, metricSample{
+			labels: [][2]string{{"status", string(key.status)}, {"category", string(key.category)}},
+			value:  float64(m.outcomes[key]),
+		})
+	}
+
+	refreshes := &metricFamily{
+		name: "chainparse_refreshes",
+		typ:  "counter",
+		help: "The refreshes of the chain data, by whether they succeeded.",
+	}
+	for _, result := range []string{"failure", "success"} {
+		refreshes.samples = append(refreshes.samples, metricSample{
+			labels: [][2]string{{"result", result}},
+			value:  float64(m.refreshes[result]),
+		})
+	}
+
+	families := []*metricFamily{moduleInfo, outcomes, refreshes}
+	if !m.lastRefresh.IsZero() {
+		families = append(families, &metricFamily{
+			name:    "chainparse_last_refresh_timestamp_seconds",
+			typ:     "gauge",
+			help:    "The Unix time at which the last successful refresh finished.",
+			samples: []metricSample{{value: float64(m.lastRefresh.UnixNano()) / 1e9}},
+		}, &metricFamily{
+			name:    "chainparse_last_refresh_duration_seconds",
+			typ:     "gauge",
+			help:    "How long the last successful refresh took.",
+			samples: []metricSample{{value: m.lastDuration.Seconds()}},
+		})
+	}
+
+	rateLimit := &metricFamily{
+		name: "chainparse_github_rate_limit_remaining",
+		typ:  "gauge",
+		help: "The requests left in the current GitHub API rate limit window, by resource.",
+	}
+	resources := make([]string, 0, len(m.rateLimit))
+	for resource := range m.rateLimit {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	for _, resource := range resources {
+		rateLimit.samples = append(rateLimit.samples, metricSample{
+			labels: [][2]string{{"resource", resource}},
+			value:  float64(m.rateLimit[resource]),
+		})
+	}
+	return append(families, rateLimit)
+}
+
+// Metrics serves the metrics of the refreshes so far in the OpenMetrics
+// text format if the request accepts it, else in the Prometheus text format.
+// It doesn't refresh the chain data itself.
+func (cp *ChainParser) Metrics(rw http.ResponseWriter, req *http.Request) {
+	openMetrics := strings.Contains(req.Header.Get("Accept"), "application/openmetrics-text")
+	if openMetrics {
+		rw.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+	} else {
+		rw.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	}
+	if err := cp.fetcher.metrics.write(rw, openMetrics); err != nil {
+		logrus.WithContext(req.Context()).WithError(err).Error("failed to send the metrics")
+	}
+}
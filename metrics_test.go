@@ -0,0 +1,96 @@
+package chainparse
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMetrics(t *testing.T) {
+	cst := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("X-RateLimit-Remaining", "4990")
+		rw.Header().Set("X-RateLimit-Resource", "graphql")
+		rw.Write([]byte("{}"))
+	}))
+	defer cst.Close()
+	destURL, _ := url.Parse(cst.URL)
+
+	cp := NewChainParser(&alwaysToURLRoundTripper{next: cst.Client(), destURL: destURL})
+	client := &http.Client{Transport: cp.fetcher.rt}
+	for _, u := range []string{githubGraphQLURL, "https://raw.githubusercontent.com/cosmos/gaia/main/go.mod"} {
+		res, err := client.Get(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+
+	cp.fetcher.metrics.recordRefresh(nil, time.Time{}, 0, errors.New("registry unavailable"))
+	cp.fetcher.metrics.recordRefresh(reportResult, reportTime, 2500*time.Millisecond, nil)
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	rec := httptest.NewRecorder()
+	cp.Metrics(rec, req)
+
+	if g, w := rec.Header().Get("Content-Type"), "application/openmetrics-text; version=1.0.0; charset=utf-8"; g != w {
+		t.Errorf("Content-Type: got %q, want %q", g, w)
+	}
+	want := `# HELP chainparse_chain_module The version of each tracked module that a chain's recommended version is built on.
+# TYPE chainparse_chain_module info
+chainparse_chain_module_info{chain="agoric",module="cosmos-sdk",version="v0.44.2-alpha.agoric.gaiad.1",replaced="github.com/agoric-labs/cosmos-sdk"} 1
+chainparse_chain_module_info{chain="agoric",module="tendermint",version="v0.34.13",replaced="github.com/tendermint/tendermint"} 1
+chainparse_chain_module_info{chain="agoric",module="ibc-go",version="v1.2.0",replaced=""} 1
+chainparse_chain_module_info{chain="cosmoshub",module="cosmos-sdk",version="v0.45.4",replaced=""} 1
+chainparse_chain_module_info{chain="cosmoshub",module="tendermint",version="v0.34.19",replaced=""} 1
+chainparse_chain_module_info{chain="cosmoshub",module="ibc-go",version="v3.0.0",replaced="github.com/cosmos/ibc-go/v3"} 1
+chainparse_chain_module_info{chain="theta",module="cosmos-sdk",version="v0.45.4",replaced=""} 1
+# HELP chainparse_fetch_outcomes The outcomes of the chains analysed by every refresh, by status and error category.
+# TYPE chainparse_fetch_outcomes counter
+chainparse_fetch_outcomes_total{status="failed",category="http"} 1
+chainparse_fetch_outcomes_total{status="ok",category=""} 3
+# HELP chainparse_refreshes The refreshes of the chain data, by whether they succeeded.
+# TYPE chainparse_refreshes counter
+chainparse_refreshes_total{result="failure"} 1
+chainparse_refreshes_total{result="success"} 1
+# HELP chainparse_last_refresh_timestamp_seconds The Unix time at which the last successful refresh finished.
+# TYPE chainparse_last_refresh_timestamp_seconds gauge
+chainparse_last_refresh_timestamp_seconds 1.6646256e+09
+# HELP chainparse_last_refresh_duration_seconds How long the last successful refresh took.
+# TYPE chainparse_last_refresh_duration_seconds gauge
+chainparse_last_refresh_duration_seconds 2.5
+# HELP chainparse_github_rate_limit_remaining The requests left in the current GitHub API rate limit window, by resource.
+# TYPE chainparse_github_rate_limit_remaining gauge
+chainparse_github_rate_limit_remaining{resource="graphql"} 4990
+# EOF
+`
+	if diff := cmp.Diff(rec.Body.String(), want); diff != "" {
+		t.Errorf("OpenMetrics mismatch: got - want +\n%s", diff)
+	}
+
+	// Prometheus' own text format has neither info metrics nor # EOF.
+	rec = httptest.NewRecorder()
+	cp.Metrics(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if g, w := rec.Header().Get("Content-Type"), "text/plain; version=0.0.4; charset=utf-8"; g != w {
+		t.Errorf("Content-Type: got %q, want %q", g, w)
+	}
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE chainparse_chain_module_info gauge\n",
+		"# TYPE chainparse_refreshes_total counter\n",
+		"chainparse_refreshes_total{result=\"success\"} 1\n",
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("Prometheus text format lacks %q:\n%s", line, body)
+		}
+	}
+	if strings.Contains(body, "# EOF") {
+		t.Errorf("Prometheus text format shouldn't end with # EOF:\n%s", body)
+	}
+}
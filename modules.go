@@ -0,0 +1,135 @@
+package chainparse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// GoModule describes the go.mod file that a chain was analysed from.
+type GoModule struct {
+	Path      string           `json:"path"`
+	GoVersion string           `json:"go_version,omitempty"`
+	Requires  []*ModuleRequire `json:"requires,omitempty"`
+	Replaces  []*ModuleReplace `json:"replaces,omitempty"`
+
+	// Sums maps the module@version of every module in go.sum to the hash
+	// of its contents, such as "h1:…", if retrieved with WithGoSums.
+	Sums map[string]string `json:"sums,omitempty"`
+}
+
+// ModuleRequire is a require directive of a go.mod file.
+type ModuleRequire struct {
+	Path     string `json:"path"`
+	Version  string `json:"version"`
+	Indirect bool   `json:"indirect,omitempty"`
+}
+
+// ModuleReplace is a replace directive of a go.mod file. OldVersion is empty
+// if every version is replaced, NewVersion if replaced with a directory.
+type ModuleReplace struct {
+	OldPath    string `json:"old_path"`
+	OldVersion string `json:"old_version,omitempty"`
+	NewPath    string `json:"new_path"`
+	NewVersion string `json:"new_version,omitempty"`
+}
+
+// WithGoModules populates ChainSchema.GoModule with every requirement and
+// replacement of the analysed go.mod files, not just the tracked ones.
+func WithGoModules() Option {
+	return func(fr *fetcher) {
+		fr.goModules = true
+	}
+}
+
+// WithGoSums is like WithGoModules but also retrieves the go.sum file next
+// to the go.mod file of each chain's recommended version, so that
+// GoModule.Sums holds the hashes of its modules. go.sum files aren't
+// available for versions that were retrieved from the module proxy.
+func WithGoSums() Option {
+	return func(fr *fetcher) {
+		fr.goModules = true
+		fr.goSums = true
+	}
+}
+
+func newGoModule(modF *modfile.File) *GoModule {
+	gm := new(GoModule)
+	if modF.Module != nil {
+		gm.Path = modF.Module.Mod.Path
+	}
+	if modF.Go != nil {
+		gm.GoVersion = modF.Go.Version
+	}
+	for _, req := range modF.Require {
+		gm.Requires = append(gm.Requires, &ModuleRequire{
+			Path:     req.Mod.Path,
+			Version:  req.Mod.Version,
+			Indirect: req.Indirect,
+		})
+	}
+	for _, rep := range modF.Replace {
+		gm.Replaces = append(gm.Replaces, &ModuleReplace{
+			OldPath:    rep.Old.Path,
+			OldVersion: rep.Old.Version,
+			NewPath:    rep.New.Path,
+			NewVersion: rep.New.Version,
+		})
+	}
+	return gm
+}
+
+// retrieveGoSum populates cs.GoModule.Sums from the go.sum file next to the
+// go.mod file that cs was analysed from. A missing go.sum isn't an error, as
+// the SBOMs it's for are still useful without hashes, so a warning is
+// returned instead.
+func (fr *fetcher) retrieveGoSum(ctx context.Context, client *http.Client, repo RepoID, cs *ChainSchema) (warning string) {
+	ref := cs.Codebase.RecommendedVersion
+	if res := cs.Resolved; res != nil {
+		if res.Source == resolvedFromProxy {
+			return "go.sum unavailable: go.mod was retrieved from the module proxy"
+		}
+		ref = res.Ref
+	}
+
+	url := repo.rawFileURL(ref, "go.sum")
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Sprintf("go.sum unavailable: %v", err)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Sprintf("go.sum unavailable: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Sprintf("go.sum unavailable: %s: HTTP request failed with status: %q", url, res.Status)
+	}
+	blob, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Sprintf("go.sum unavailable: %v", err)
+	}
+	cs.GoModule.Sums = parseGoSum(blob)
+	return ""
+}
+
+// parseGoSum returns the hashes of the module contents listed in a go.sum
+// file by module@version, skipping the hashes of their go.mod files.
+func parseGoSum(blob []byte) map[string]string {
+	sums := make(map[string]string)
+	sc := bufio.NewScanner(bytes.NewReader(blob))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) != 3 || strings.HasSuffix(fields[1], "/go.mod") {
+			continue
+		}
+		sums[fields[0]+"@"+fields[1]] = fields[2]
+	}
+	return sums
+}
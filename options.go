@@ -0,0 +1,22 @@
+package chainparse
+
+// Option configures how chain data is fetched, see NewChainParser and RetrieveChainData.
+type Option func(*fetcher)
+
+// WithGitHubToken authenticates requests to the GitHub API with token.
+// With a token, repository metadata and go.mod files are looked up in
+// batches via the GraphQL API instead of a request per repository.
+func WithGitHubToken(token string) Option {
+	return func(fr *fetcher) {
+		fr.githubToken = token
+	}
+}
+
+// WithProgress calls fn with the Outcome of every chain as soon as it's known,
+// along with the chain itself if it was analysed successfully, so that results
+// can be streamed before the whole registry is done. Calls are never concurrent.
+func WithProgress(fn func(*ChainSchema, *Outcome)) Option {
+	return func(fr *fetcher) {
+		fr.progress = fn
+	}
+}
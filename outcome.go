@@ -0,0 +1,144 @@
+package chainparse
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// OutcomeStatus is whether a chain from the registry made it into the results.
+type OutcomeStatus string
+
+const (
+	OutcomeOK      OutcomeStatus = "ok"
+	OutcomeSkipped OutcomeStatus = "skipped"
+	OutcomeFailed  OutcomeStatus = "failed"
+)
+
+// ErrorCategory classifies why a chain was skipped or failed.
+type ErrorCategory string
+
+const (
+	// CategoryNoCodebase is for registry entries without a codebase, such as non-Cosmos chains.
+	CategoryNoCodebase ErrorCategory = "no_codebase"
+
+	// CategoryInvalidChainJSON is for chain.json files that couldn't be decoded.
+	CategoryInvalidChainJSON ErrorCategory = "invalid_chain_json"
+
+	// CategoryInvalidRepo is for git_repo values that aren't usable repository URLs.
+	CategoryInvalidRepo ErrorCategory = "invalid_repo"
+
+	// CategoryHTTP is for requests that were answered with a non-2XX status.
+	CategoryHTTP ErrorCategory = "http"
+
+	// CategoryNetwork is for requests that didn't get an answer at all.
+	CategoryNetwork ErrorCategory = "network"
+
+	// CategoryParse is for go.mod files that couldn't be parsed.
+	CategoryParse ErrorCategory = "parse"
+
+	// CategoryUnknown is for every other failure.
+	CategoryUnknown ErrorCategory = "unknown"
+)
+
+// FetchError is a categorized failure to retrieve or analyse a chain's data.
+type FetchError struct {
+	Category   ErrorCategory
+	URL        string
+	HTTPStatus int
+	Err        error
+}
+
+func (fe *FetchError) Error() string {
+	if fe.URL == "" {
+		return fe.Err.Error()
+	}
+	return fmt.Sprintf("%s: %v", fe.URL, fe.Err)
+}
+
+func (fe *FetchError) Unwrap() error { return fe.Err }
+
+// Outcome reports what happened to a single chain from the registry.
+type Outcome struct {
+	ChainName string `json:"chain_name"`
+
+	// Path is the path of the chain.json file within the registry.
+	Path string `json:"path,omitempty"`
+
+	Status     OutcomeStatus `json:"status"`
+	Category   ErrorCategory `json:"category,omitempty"`
+	HTTPStatus int           `json:"http_status,omitempty"`
+	URL        string        `json:"url,omitempty"`
+	Message    string        `json:"message,omitempty"`
+
+	// Warnings are problems that didn't prevent the chain from
+	// being reported, such as a failed latest analysis.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func newOutcome(chainName, path string, err error) *Outcome {
+	out := &Outcome{ChainName: chainName, Path: path, Status: OutcomeOK}
+	if err == nil {
+		return out
+	}
+	out.Status = OutcomeFailed
+	out.Category = CategoryUnknown
+	out.Message = err.Error()
+
+	var fe *FetchError
+	if errors.As(err, &fe) {
+		out.Category = fe.Category
+		out.URL = fe.URL
+		out.HTTPStatus = fe.HTTPStatus
+		out.Message = fe.Err.Error()
+	}
+	return out
+}
+
+// Result is the outcome of a whole run over the registry: the chains that were
+// successfully analysed, and an Outcome for every chain found in the registry.
+type Result struct {
+	Chains   []*ChainSchema `json:"chains"`
+	Outcomes []*Outcome     `json:"outcomes"`
+}
+
+// Counts returns the number of outcomes with each status.
+func (res *Result) Counts() map[OutcomeStatus]int {
+	counts := map[OutcomeStatus]int{OutcomeOK: 0, OutcomeSkipped: 0, OutcomeFailed: 0}
+	for _, out := range res.Outcomes {
+		counts[out.Status]++
+	}
+	return counts
+}
+
+// Failures returns the outcomes of the chains that failed.
+func (res *Result) Failures() []*Outcome {
+	var failures []*Outcome
+	for _, out := range res.Outcomes {
+		if out.Status == OutcomeFailed {
+			failures = append(failures, out)
+		}
+	}
+	return failures
+}
+
+// Summary returns a one line summary of the outcomes such as "120 ok, 5 skipped, 12 failed".
+func (res *Result) Summary() string {
+	counts := res.Counts()
+	parts := make([]string, 0, len(counts))
+	for _, status := range []OutcomeStatus{OutcomeOK, OutcomeSkipped, OutcomeFailed} {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[status], status))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func sortOutcomes(outcomes []*Outcome) {
+	sort.Slice(outcomes, func(i, j int) bool {
+		oi, oj := outcomes[i], outcomes[j]
+		if oi.ChainName != oj.ChainName {
+			return oi.ChainName < oj.ChainName
+		}
+		return oi.Path < oj.Path
+	})
+}
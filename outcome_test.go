@@ -0,0 +1,131 @@
+package chainparse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFetchResultOutcomes(t *testing.T) {
+	cst := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch p := req.URL.Path; {
+		case strings.HasPrefix(p, "/AIOZNetwork/"):
+			http.NotFound(rw, req)
+		case strings.HasPrefix(p, "/umee-network/"):
+			rw.Write([]byte("module github.com/umee-network/umee\n\nrequire (\n"))
+		case strings.HasSuffix(p, "go.mod"):
+			rw.Write(testdataGoMod)
+		default:
+			rw.Write(testdataZip)
+		}
+	}))
+	defer cst.Close()
+
+	destURL, err := url.Parse(cst.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	art := &alwaysToURLRoundTripper{next: cst.Client(), destURL: destURL}
+	res, err := newFetcher(art).fetchResult(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g, w := res.Summary(), "7 ok, 1 skipped, 2 failed"; g != w {
+		t.Fatalf("Summary mismatch: got %q, want %q", g, w)
+	}
+	if g, w := len(res.Chains), 7; g != w {
+		t.Fatalf("Chains: got %d, want %d", g, w)
+	}
+
+	failures := res.Failures()
+	for _, out := range failures {
+		if out.Message == "" {
+			t.Errorf("%s: expected a message", out.ChainName)
+		}
+		out.Message = ""
+	}
+	wantFailures := []*Outcome{
+		{
+			ChainName:  "aioz",
+			Path:       "chain-registry-master/aioz/chain.json",
+			Status:     OutcomeFailed,
+			Category:   CategoryHTTP,
+			HTTPStatus: http.StatusNotFound,
+			URL:        "https://raw.githubusercontent.com/AIOZNetwork/go-aioz/v1.2.0/go.mod",
+		},
+		{
+			ChainName: "umee",
+			Path:      "chain-registry-master/umee/chain.json",
+			Status:    OutcomeFailed,
+			Category:  CategoryParse,
+			URL:       "https://raw.githubusercontent.com/umee-network/umee/v1.0.3/go.mod",
+		},
+	}
+	if diff := cmp.Diff(failures, wantFailures); diff != "" {
+		t.Fatalf("Failures mismatch: got - want +\n%s", diff)
+	}
+
+	var skipped *Outcome
+	for _, out := range res.Outcomes {
+		if out.Status == OutcomeSkipped {
+			skipped = out
+		}
+	}
+	wantSkipped := &Outcome{
+		ChainName: "bitcoin",
+		Path:      "chain-registry-master/_non-cosmos/bitcoin/chain.json",
+		Status:    OutcomeSkipped,
+		Category:  CategoryNoCodebase,
+		Message:   "the chain.json has no codebase",
+	}
+	if diff := cmp.Diff(skipped, wantSkipped); diff != "" {
+		t.Fatalf("Skipped mismatch: got - want +\n%s", diff)
+	}
+}
+
+func TestFetchResultProgress(t *testing.T) {
+	cst := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch p := req.URL.Path; {
+		case strings.HasPrefix(p, "/AIOZNetwork/"):
+			http.NotFound(rw, req)
+		case strings.HasSuffix(p, "go.mod"):
+			rw.Write(testdataGoMod)
+		default:
+			rw.Write(testdataZip)
+		}
+	}))
+	defer cst.Close()
+
+	destURL, err := url.Parse(cst.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var streamed []*ChainSchema
+	var outcomes []*Outcome
+	progress := func(cs *ChainSchema, out *Outcome) {
+		if cs != nil {
+			streamed = append(streamed, cs)
+		}
+		outcomes = append(outcomes, out)
+	}
+	art := &alwaysToURLRoundTripper{next: cst.Client(), destURL: destURL}
+	res, err := newFetcher(art, WithProgress(progress)).fetchResult(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every chain and outcome must have been streamed, in whatever order they finished.
+	sortOutcomes(outcomes)
+	if diff := cmp.Diff(outcomes, res.Outcomes); diff != "" {
+		t.Errorf("Outcomes mismatch: got - want +\n%s", diff)
+	}
+	if g, w := len(streamed), len(res.Chains); g != w {
+		t.Errorf("Streamed chains: got %d, want %d", g, w)
+	}
+}
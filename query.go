@@ -0,0 +1,293 @@
+package chainparse
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// queryModules maps the module query parameters of /v1/chains to the tracked dependencies.
+var queryModules = map[string]string{
+	"cosmos_sdk": moduleCosmosSDK,
+	"tendermint": moduleTendermint,
+	"cometbft":   moduleTendermint,
+	"ibc":        moduleIBC,
+}
+
+// versionConstraint is a comparison such as >=v0.47 of a module's version.
+// Without an operator, any version of the release line matches, v0.47
+// matching v0.47.0 and v0.47.5 but not v0.470.0.
+type versionConstraint struct {
+	op      string
+	version string
+}
+
+func parseVersionConstraint(s string) (versionConstraint, error) {
+	var vc versionConstraint
+	for _, op := range []string{">=", "<=", "!=", "==", ">", "<", "="} {
+		if strings.HasPrefix(s, op) {
+			vc.op, s = op, s[len(op):]
+			break
+		}
+	}
+	if vc.op == "==" {
+		vc.op = "="
+	}
+	vc.version = strings.TrimSpace(s)
+	if !strings.HasPrefix(vc.version, "v") {
+		vc.version = "v" + vc.version
+	}
+	if !semver.IsValid(vc.version) {
+		return vc, fmt.Errorf("invalid version %q", s)
+	}
+	return vc, nil
+}
+
+func (vc versionConstraint) match(version string) bool {
+	if !semver.IsValid(version) {
+		return false
+	}
+	if vc.op == "" {
+		return version == vc.version || strings.HasPrefix(version, vc.version+".") || strings.HasPrefix(version, vc.version+"-")
+	}
+	c := semver.Compare(version, vc.version)
+	switch vc.op {
+	case ">=":
+		return c >= 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case "<":
+		return c < 0
+	case "!=":
+		return c != 0
+	default:
+		return c == 0
+	}
+}
+
+// chainSortKeys are the keys that /v1/chains can be sorted by.
+var chainSortKeys = map[string]func(*APIChain) string{
+	"chain_name":   func(ac *APIChain) string { return ac.ChainName },
+	"pretty_name":  func(ac *APIChain) string { return ac.PrettyName },
+	"network_type": func(ac *APIChain) string { return ac.NetworkType },
+	"status":       func(ac *APIChain) string { return ac.Status },
+	"fetch_status": func(ac *APIChain) string { return string(ac.fetchStatus()) },
+	"cosmos_sdk":   func(ac *APIChain) string { return ac.CosmosSDKVersion },
+	"tendermint":   func(ac *APIChain) string { return ac.TendermintVersion },
+	"cometbft":     func(ac *APIChain) string { return ac.TendermintVersion },
+	"ibc":          func(ac *APIChain) string { return ac.IBCVersion },
+}
+
+type chainSortKey struct {
+	name string
+	desc bool
+}
+
+// compare compares a and b by the key, versions by semver.
+func (sk chainSortKey) compare(a, b *APIChain) int {
+	va, vb := chainSortKeys[sk.name](a), chainSortKeys[sk.name](b)
+	var c int
+	if _, ok := queryModules[sk.name]; ok {
+		va, _ = splitDependency(va)
+		vb, _ = splitDependency(vb)
+		c = semver.Compare(va, vb)
+	} else {
+		c = strings.Compare(va, vb)
+	}
+	if sk.desc {
+		return -c
+	}
+	return c
+}
+
+// chainQuery is what the query parameters of /v1/chains ask for.
+type chainQuery struct {
+	networkTypes  []string
+	statuses      []string
+	fetchStatuses []string
+	constraints   map[string][]versionConstraint
+	replaced      *bool
+	forked        *bool
+
+	sort   []chainSortKey
+	limit  int
+	cursor string
+}
+
+// parseChainQuery parses the query parameters of /v1/chains:
+//
+//	network_type=mainnet,testnet     any of the network types
+//	status=live                      any of the registry statuses
+//	fetch_status=ok,failed           any of the outcome statuses
+//	cosmos_sdk=>=0.47,<0.50          version constraints, all of which must
+//	                                 hold, likewise tendermint (or cometbft) and ibc
+//	replaced=true                    whether any module was replaced
+//	forked=false                     whether any module was replaced by a fork
+//	sort=-cosmos_sdk,chain_name      sort keys, descending if prefixed with -
+//	limit=50&cursor=…                pagination, the cursor being next_cursor
+func parseChainQuery(query url.Values) (*chainQuery, error) {
+	cq := &chainQuery{constraints: make(map[string][]versionConstraint)}
+	split := func(list string) []string {
+		var elems []string
+		for _, elem := range strings.Split(list, ",") {
+			if elem = strings.TrimSpace(elem); elem != "" {
+				elems = append(elems, elem)
+			}
+		}
+		return elems
+	}
+	for key, values := range query {
+		value := strings.Join(values, ",")
+		switch key {
+		case "network_type":
+			cq.networkTypes = split(value)
+		case "status":
+			cq.statuses = split(value)
+		case "fetch_status":
+			for _, status := range split(value) {
+				switch OutcomeStatus(status) {
+				case OutcomeOK, OutcomeSkipped, OutcomeFailed:
+				default:
+					return nil, fmt.Errorf("invalid fetch_status %q, expected ok, skipped or failed", status)
+				}
+				cq.fetchStatuses = append(cq.fetchStatuses, status)
+			}
+		case "cosmos_sdk", "tendermint", "cometbft", "ibc":
+			for _, elem := range split(value) {
+				vc, err := parseVersionConstraint(elem)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", key, err)
+				}
+				module := queryModules[key]
+				cq.constraints[module] = append(cq.constraints[module], vc)
+			}
+		case "replaced", "forked":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q, expected true or false", key, value)
+			}
+			if key == "replaced" {
+				cq.replaced = &b
+			} else {
+				cq.forked = &b
+			}
+		case "sort":
+			for _, name := range split(value) {
+				sk := chainSortKey{name: strings.TrimPrefix(name, "-"), desc: strings.HasPrefix(name, "-")}
+				if chainSortKeys[sk.name] == nil {
+					return nil, fmt.Errorf("unknown sort key %q", sk.name)
+				}
+				cq.sort = append(cq.sort, sk)
+			}
+		case "limit":
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit <= 0 {
+				return nil, fmt.Errorf("invalid limit %q, expected a positive number", value)
+			}
+			cq.limit = limit
+		case "cursor":
+			cq.cursor = value
+		default:
+			return nil, fmt.Errorf("unknown query parameter %q", key)
+		}
+	}
+	return cq, nil
+}
+
+// fetchStatus is the status of the chain's Outcome.
+func (ac *APIChain) fetchStatus() OutcomeStatus {
+	if ac.Outcome == nil {
+		return OutcomeOK
+	}
+	return ac.Outcome.Status
+}
+
+func (cq *chainQuery) match(ac *APIChain) bool {
+	if len(cq.networkTypes) != 0 && !stringsContain(cq.networkTypes, ac.NetworkType) {
+		return false
+	}
+	if len(cq.statuses) != 0 && !stringsContain(cq.statuses, ac.Status) {
+		return false
+	}
+	if len(cq.fetchStatuses) != 0 && !stringsContain(cq.fetchStatuses, string(ac.fetchStatus())) {
+		return false
+	}
+	deps := map[string]string{
+		moduleCosmosSDK:  ac.CosmosSDKVersion,
+		moduleTendermint: ac.TendermintVersion,
+		moduleIBC:        ac.IBCVersion,
+	}
+	var replaced, forked bool
+	for module, dep := range deps {
+		version, replacement := splitDependency(dep)
+		for _, vc := range cq.constraints[module] {
+			if !vc.match(version) {
+				return false
+			}
+		}
+		replaced = replaced || replacement != ""
+		forked = forked || isForkedDependency(dep)
+	}
+	return (cq.replaced == nil || *cq.replaced == replaced) &&
+		(cq.forked == nil || *cq.forked == forked)
+}
+
+// less orders chains by the sort keys, and then by chain_name which is unique.
+func (cq *chainQuery) less(a, b *APIChain) bool {
+	for _, sk := range cq.sort {
+		if c := sk.compare(a, b); c != 0 {
+			return c < 0
+		}
+	}
+	return a.ChainName < b.ChainName
+}
+
+func encodeCursor(chainName string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(chainName))
+}
+
+// apply filters, sorts and paginates chains, returning the page, the number
+// of chains matching the filters and the cursor of the next page, if any.
+// The cursor is the chain_name of the last chain of the page, so that the
+// next page carries on after that chain even if the chains were refreshed
+// in the meantime, as long as it's still around.
+func (cq *chainQuery) apply(chains []*APIChain) (page []*APIChain, total int, next string, err error) {
+	for _, ac := range chains {
+		if cq.match(ac) {
+			page = append(page, ac)
+		}
+	}
+	sort.Slice(page, func(i, j int) bool { return cq.less(page[i], page[j]) })
+	total = len(page)
+
+	if cq.cursor != "" {
+		name, err := base64.RawURLEncoding.DecodeString(cq.cursor)
+		if err != nil {
+			return nil, 0, "", fmt.Errorf("invalid cursor %q", cq.cursor)
+		}
+		var after *APIChain
+		for _, ac := range chains {
+			if ac.ChainName == string(name) {
+				after = ac
+				break
+			}
+		}
+		if after == nil {
+			return nil, 0, "", fmt.Errorf("invalid cursor %q, chain %q is gone", cq.cursor, name)
+		}
+		i := sort.Search(len(page), func(i int) bool { return cq.less(after, page[i]) })
+		page = page[i:]
+	}
+	if cq.limit > 0 && len(page) > cq.limit {
+		page = page[:cq.limit]
+		next = encodeCursor(page[len(page)-1].ChainName)
+	}
+	return page, total, next, nil
+}
@@ -0,0 +1,124 @@
+package chainparse
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestVersionConstraint(t *testing.T) {
+	tests := []struct {
+		constraint, version string
+		want                bool
+	}{
+		{">=0.47", "v0.47.0", true},
+		{">=0.47", "v0.46.13", false},
+		{">=v0.45.4", "v0.45.4", true},
+		{"<0.45", "v0.44.2-alpha.agoric.gaiad.1", true},
+		{"0.45", "v0.45.4", true},
+		{"0.4", "v0.45.4", false},
+		{"=0.45.4", "v0.45.4", true},
+		{"!=0.45.4", "v0.45.4", false},
+		{">0.30", "", false},
+		{">0.30", "agoric-3.1", false},
+	}
+	for _, tt := range tests {
+		vc, err := parseVersionConstraint(tt.constraint)
+		if err != nil {
+			t.Errorf("%q: %v", tt.constraint, err)
+			continue
+		}
+		if got := vc.match(tt.version); got != tt.want {
+			t.Errorf("%q matching %q: got %t, want %t", tt.constraint, tt.version, got, tt.want)
+		}
+	}
+	if _, err := parseVersionConstraint(">=latest"); err == nil {
+		t.Error("Expected an error for an invalid version")
+	}
+}
+
+func TestChainsQuery(t *testing.T) {
+	cp := apiChainParser()
+	tests := []struct {
+		query      string
+		wantStatus int
+		want       []string
+		wantTotal  int
+		wantNext   bool
+	}{
+		{query: "network_type=mainnet", wantStatus: http.StatusOK, want: []string{"agoric", "cosmoshub"}, wantTotal: 2},
+		{query: "cosmos_sdk=>=0.45", wantStatus: http.StatusOK, want: []string{"cosmoshub", "theta"}, wantTotal: 2},
+		{query: "cosmos_sdk=>=0.44,<0.45", wantStatus: http.StatusOK, want: []string{"agoric"}, wantTotal: 1},
+		{query: "cometbft=0.34&ibc=>=3", wantStatus: http.StatusOK, want: []string{"cosmoshub"}, wantTotal: 1},
+		{query: "replaced=true", wantStatus: http.StatusOK, want: []string{"agoric", "cosmoshub"}, wantTotal: 2},
+		{query: "forked=true", wantStatus: http.StatusOK, want: []string{"agoric"}, wantTotal: 1},
+		{query: "forked=false&fetch_status=ok", wantStatus: http.StatusOK, want: []string{"cosmoshub", "theta"}, wantTotal: 2},
+		{query: "fetch_status=failed", wantStatus: http.StatusOK, want: []string{"aioz"}, wantTotal: 1},
+		{query: "sort=-cosmos_sdk", wantStatus: http.StatusOK, want: []string{"cosmoshub", "theta", "agoric", "aioz"}, wantTotal: 4},
+		{query: "sort=-network_type,-chain_name&limit=3", wantStatus: http.StatusOK, want: []string{"theta", "cosmoshub", "agoric"}, wantTotal: 4, wantNext: true},
+		{query: "status=live", wantStatus: http.StatusOK, want: []string{}, wantTotal: 0},
+		{query: "fetch_status=broken", wantStatus: http.StatusBadRequest},
+		{query: "cosmos_sdk=>=latest", wantStatus: http.StatusBadRequest},
+		{query: "sort=stars", wantStatus: http.StatusBadRequest},
+		{query: "limit=0", wantStatus: http.StatusBadRequest},
+		{query: "networktype=mainnet", wantStatus: http.StatusBadRequest},
+		{query: "cursor=" + encodeCursor("osmosis"), wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		cp.Chains(rec, httptest.NewRequest("GET", "/v1/chains?"+tt.query, nil))
+		if g, w := rec.Code, tt.wantStatus; g != w {
+			t.Errorf("%s: status: got %d, want %d\n%s", tt.query, g, w, rec.Body)
+			continue
+		}
+		if tt.want == nil {
+			continue
+		}
+		var list APIChainList
+		if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		names := []string{}
+		for _, ac := range list.Chains {
+			names = append(names, ac.ChainName)
+		}
+		if diff := cmp.Diff(names, tt.want); diff != "" {
+			t.Errorf("%s: chains mismatch: got - want +\n%s", tt.query, diff)
+		}
+		if g, w := list.Total, tt.wantTotal; g != w {
+			t.Errorf("%s: total: got %d, want %d", tt.query, g, w)
+		}
+		if g, w := list.NextCursor != "", tt.wantNext; g != w {
+			t.Errorf("%s: next cursor: got %q, want one: %t", tt.query, list.NextCursor, w)
+		}
+	}
+}
+
+func TestChainsPagination(t *testing.T) {
+	cp := apiChainParser()
+	var names []string
+	for cursor, pages := "", 0; ; pages++ {
+		if pages > 4 {
+			t.Fatal("Too many pages")
+		}
+		rec := httptest.NewRecorder()
+		cp.Chains(rec, httptest.NewRequest("GET", "/v1/chains?sort=network_type&limit=1&cursor="+cursor, nil))
+		var list APIChainList
+		if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+			t.Fatalf("%v\n%s", err, rec.Body)
+		}
+		for _, ac := range list.Chains {
+			names = append(names, ac.ChainName)
+		}
+		if cursor = list.NextCursor; cursor == "" {
+			break
+		}
+	}
+	want := []string{"aioz", "agoric", "cosmoshub", "theta"}
+	if diff := cmp.Diff(names, want); diff != "" {
+		t.Errorf("Chains mismatch: got - want +\n%s", diff)
+	}
+}
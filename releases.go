@@ -0,0 +1,335 @@
+package chainparse
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+const goProxyURL = "https://proxy.golang.org"
+
+// ReleaseSource is where the versions of a chain's repository are discovered from.
+type ReleaseSource string
+
+const (
+	// ReleaseSourceGit lists the repository's tags via the git smart HTTP protocol.
+	ReleaseSourceGit ReleaseSource = "git"
+
+	// ReleaseSourceGitHub lists the tags of the repository's releases, excluding
+	// drafts, via the GitHub API.
+	ReleaseSourceGitHub ReleaseSource = "github"
+
+	// ReleaseSourceProxy lists the versions of the chain's Go module known to the module proxy.
+	ReleaseSourceProxy ReleaseSource = "proxy"
+)
+
+// ReleaseSources lists all the supported release sources.
+var ReleaseSources = []ReleaseSource{ReleaseSourceGit, ReleaseSourceGitHub, ReleaseSourceProxy}
+
+// ParseReleaseSources parses a comma separated list of release sources.
+func ParseReleaseSources(names string) ([]ReleaseSource, error) {
+	var sources []ReleaseSource
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, source := range ReleaseSources {
+			if ReleaseSource(name) == source {
+				sources, known = append(sources, source), true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown release source %q, expected one of %q", name, ReleaseSources)
+		}
+	}
+	return sources, nil
+}
+
+// WithReleaseDiscovery enables listing every chain's releases from sources,
+// populating ChainSchema.Releases. All the sources are used if none are passed.
+func WithReleaseDiscovery(sources ...ReleaseSource) Option {
+	return func(fr *fetcher) {
+		if len(sources) == 0 {
+			sources = ReleaseSources
+		}
+		fr.releaseSources = sources
+	}
+}
+
+func (fr *fetcher) discoversReleasesFrom(source ReleaseSource) bool {
+	for _, s := range fr.releaseSources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
+
+// ReleaseSummary describes the versions released by a chain's repository
+// and how far the registry's recommended_version lags behind them.
+type ReleaseSummary struct {
+	// Sources lists the sources that the versions were discovered from.
+	Sources []ReleaseSource `json:"sources,omitempty"`
+
+	// Versions holds every discovered semver version, newest first.
+	Versions []string `json:"versions,omitempty"`
+
+	NewestStable     string `json:"newest_stable,omitempty"`
+	NewestPrerelease string `json:"newest_prerelease,omitempty"`
+
+	// StableReleasesBehind is the number of stable versions newer than
+	// recommended_version, or -1 if recommended_version isn't a version.
+	StableReleasesBehind int `json:"stable_releases_behind"`
+
+	// Lag is the most significant semver component by which recommended_version
+	// trails NewestStable: "major", "minor", "patch", "none" or "unknown".
+	Lag string `json:"lag"`
+
+	// Errors holds the failures of individual sources, keyed by source.
+	Errors map[ReleaseSource]string `json:"errors,omitempty"`
+}
+
+// The values of ReleaseSummary.Lag.
+const (
+	LagNone    = "none"
+	LagPatch   = "patch"
+	LagMinor   = "minor"
+	LagMajor   = "major"
+	LagUnknown = "unknown"
+)
+
+// summarizeReleases sorts the discovered tags by semver and compares recommended against them.
+func summarizeReleases(tags []string, recommended string) *ReleaseSummary {
+	rs := &ReleaseSummary{StableReleasesBehind: -1, Lag: LagUnknown}
+
+	byVersion := make(map[string]string)
+	for _, tag := range tags {
+		vers := tagSemver(tag)
+		if vers == "" {
+			continue
+		}
+		// Prefer the "v" prefixed spelling when a repository has both.
+		if prev, ok := byVersion[vers]; !ok || !strings.HasPrefix(prev, "v") {
+			byVersion[vers] = tag
+		}
+	}
+	canonical := make([]string, 0, len(byVersion))
+	for vers := range byVersion {
+		canonical = append(canonical, vers)
+	}
+	sort.Slice(canonical, func(i, j int) bool {
+		return semver.Compare(canonical[i], canonical[j]) > 0
+	})
+	for _, vers := range canonical {
+		tag := byVersion[vers]
+		rs.Versions = append(rs.Versions, tag)
+		if semver.Prerelease(vers) == "" {
+			if rs.NewestStable == "" {
+				rs.NewestStable = tag
+			}
+		} else if rs.NewestPrerelease == "" {
+			rs.NewestPrerelease = tag
+		}
+	}
+
+	recVers := tagSemver(recommended)
+	if recVers == "" {
+		return rs
+	}
+	rs.StableReleasesBehind = 0
+	for _, vers := range canonical {
+		if semver.Prerelease(vers) == "" && semver.Compare(vers, recVers) > 0 {
+			rs.StableReleasesBehind++
+		}
+	}
+	if rs.NewestStable == "" {
+		return rs
+	}
+	newest := tagSemver(rs.NewestStable)
+	switch {
+	case semver.Compare(newest, recVers) <= 0:
+		rs.Lag = LagNone
+	case semver.Major(newest) != semver.Major(recVers):
+		rs.Lag = LagMajor
+	case semver.MajorMinor(newest) != semver.MajorMinor(recVers):
+		rs.Lag = LagMinor
+	default:
+		rs.Lag = LagPatch
+	}
+	return rs
+}
+
+// retrieveReleases lists the versions of cs's repository from every configured source.
+// A source failing doesn't fail the others, its error is recorded in the summary.
+func (fr *fetcher) retrieveReleases(ctx context.Context, client *http.Client, repo RepoID, cs *ChainSchema) *ReleaseSummary {
+	var tags []string
+	var sources []ReleaseSource
+	errs := make(map[ReleaseSource]string)
+	for _, source := range fr.releaseSources {
+		var found []string
+		var err error
+		switch source {
+		case ReleaseSourceGit:
+			var refs *gitRefs
+			if refs, err = fr.lsRemote(ctx, repo); err == nil {
+				found = refs.Tags()
+			}
+		case ReleaseSourceGitHub:
+			found, err = fr.githubReleases(ctx, client, repo)
+		case ReleaseSourceProxy:
+			found, err = fr.proxyVersions(ctx, client, fr.modulePath(repo, cs))
+		}
+		if err != nil {
+			errs[source] = err.Error()
+			continue
+		}
+		sources = append(sources, source)
+		tags = append(tags, found...)
+	}
+
+	rs := summarizeReleases(tags, cs.Codebase.RecommendedVersion)
+	rs.Sources = sources
+	if len(errs) != 0 {
+		rs.Errors = errs
+	}
+	return rs
+}
+
+// modulePath returns the module path declared by the go.mod of cs's
+// recommended version, or "" if that go.mod wasn't retrieved. The go.mod is
+// looked up at the ref that the version resolved to, if any.
+func (fr *fetcher) modulePath(repo RepoID, cs *ChainSchema) string {
+	if cs.GoModule != nil && cs.GoModule.Path != "" {
+		return cs.GoModule.Path
+	}
+	ref := cs.Codebase.RecommendedVersion
+	if res := cs.Resolved; res != nil {
+		switch {
+		case res.Source == resolvedFromProxy:
+			// The module proxy only serves a go.mod under the module path it declares.
+			modPath, _, _ := strings.Cut(res.Module, "@")
+			return modPath
+		case res.Ref != "":
+			ref = res.Ref
+		}
+	}
+	modBlob, ok := fr.cachedGoMod(repo, ref)
+	if !ok {
+		fr.mu.Lock()
+		modBlob, ok = fr.modCache[modCacheKey(repo, ref)]
+		fr.mu.Unlock()
+	}
+	if !ok {
+		return ""
+	}
+	return modfile.ModulePath(modBlob)
+}
+
+func (fr *fetcher) githubReleases(ctx context.Context, client *http.Client, repo RepoID) ([]string, error) {
+	releases, err := fr.githubReleaseList(ctx, client, repo)
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]string, 0, len(releases))
+	for _, release := range releases {
+		tags = append(tags, release.TagName)
+	}
+	return tags, nil
+}
+
+// githubReleaseList returns the repository's newest releases, excluding drafts.
+func (fr *fetcher) githubReleaseList(ctx context.Context, client *http.Client, repo RepoID) ([]githubRelease, error) {
+	if ghRepo := fr.cachedGitHubRepo(repo); ghRepo != nil && ghRepo.Releases != nil {
+		return ghRepo.Releases, nil
+	}
+
+	if !repo.IsGitHub() {
+		return nil, fmt.Errorf("not a GitHub repository: %q", repo)
+	}
+	owner, name := repo.Owner, repo.Repo
+	apiURL := githubAPIURL + path.Join("/repos", owner, name, "releases") + "?per_page=100"
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if fr.githubToken != "" {
+		req.Header.Set("Authorization", "Bearer "+fr.githubToken)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	blob, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("GitHub releases request failed with status: %q", res.Status)
+	}
+	var ghReleases []struct {
+		TagName string `json:"tag_name"`
+		Name    string `json:"name"`
+		Draft   bool   `json:"draft"`
+	}
+	if err := json.Unmarshal(blob, &ghReleases); err != nil {
+		return nil, err
+	}
+	releases := make([]githubRelease, 0, len(ghReleases))
+	for _, release := range ghReleases {
+		if !release.Draft {
+			releases = append(releases, githubRelease{TagName: release.TagName, Name: release.Name})
+		}
+	}
+
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	ghRepo := fr.githubRepoEntry(repo)
+	ghRepo.Releases = releases
+	return releases, nil
+}
+
+// proxyVersions lists the versions of modPath known to the module proxy.
+func (fr *fetcher) proxyVersions(ctx context.Context, client *http.Client, modPath string) ([]string, error) {
+	if modPath == "" {
+		return nil, fmt.Errorf("the module path is unknown")
+	}
+	escaped, err := module.EscapePath(modPath)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", goProxyURL+"/"+escaped+"/@v/list", nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("module proxy request for %q failed with status: %q", modPath, res.Status)
+	}
+	var versions []string
+	sc := bufio.NewScanner(res.Body)
+	for sc.Scan() {
+		if vers := strings.TrimSpace(sc.Text()); vers != "" {
+			versions = append(versions, vers)
+		}
+	}
+	return versions, sc.Err()
+}
@@ -0,0 +1,151 @@
+package chainparse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSummarizeReleases(t *testing.T) {
+	tags := []string{"v1.0.0", "1.0.0", "v1.1.0", "v1.1.1", "v2.0.0-rc.1", "v1.2.0", "agoric-3.1", "nightly"}
+	tests := []struct {
+		recommended string
+		want        *ReleaseSummary
+	}{
+		{
+			recommended: "1.1.0",
+			want: &ReleaseSummary{
+				Versions:             []string{"v2.0.0-rc.1", "v1.2.0", "v1.1.1", "v1.1.0", "v1.0.0"},
+				NewestStable:         "v1.2.0",
+				NewestPrerelease:     "v2.0.0-rc.1",
+				StableReleasesBehind: 2,
+				Lag:                  LagMinor,
+			},
+		},
+		{
+			recommended: "v1.2.0",
+			want: &ReleaseSummary{
+				Versions:             []string{"v2.0.0-rc.1", "v1.2.0", "v1.1.1", "v1.1.0", "v1.0.0"},
+				NewestStable:         "v1.2.0",
+				NewestPrerelease:     "v2.0.0-rc.1",
+				StableReleasesBehind: 0,
+				Lag:                  LagNone,
+			},
+		},
+		{
+			recommended: "agoric-3.1",
+			want: &ReleaseSummary{
+				Versions:             []string{"v2.0.0-rc.1", "v1.2.0", "v1.1.1", "v1.1.0", "v1.0.0"},
+				NewestStable:         "v1.2.0",
+				NewestPrerelease:     "v2.0.0-rc.1",
+				StableReleasesBehind: -1,
+				Lag:                  LagUnknown,
+			},
+		},
+	}
+	for _, tt := range tests {
+		if diff := cmp.Diff(summarizeReleases(tags, tt.recommended), tt.want); diff != "" {
+			t.Errorf("%s: mismatch: got - want +\n%s", tt.recommended, diff)
+		}
+	}
+
+	if g, w := summarizeReleases([]string{"v0.44.1", "v0.45.0"}, "v0.44.0").Lag, LagMinor; g != w {
+		t.Errorf("Lag: got %q, want %q", g, w)
+	}
+	if g, w := summarizeReleases([]string{"v5.0.0", "v6.0.4"}, "v6.0.0").Lag, LagPatch; g != w {
+		t.Errorf("Lag: got %q, want %q", g, w)
+	}
+	if g, w := summarizeReleases([]string{"v6.0.0", "v7.0.0"}, "v6.0.0").Lag, LagMajor; g != w {
+		t.Errorf("Lag: got %q, want %q", g, w)
+	}
+}
+
+func TestRetrieveReleases(t *testing.T) {
+	cst := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/cosmos/gaia/info/refs":
+			rw.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
+			rw.Write([]byte(pktLines(
+				"# service=git-upload-pack\n",
+				"",
+				oidMain+" HEAD\x00symref=HEAD:refs/heads/main\n",
+				oidMain+" refs/heads/main\n",
+				oidMain+" refs/tags/v6.0.0\n",
+				oidMain+" refs/tags/v6.0.4\n",
+				"",
+			)))
+		case "/repos/cosmos/gaia/releases":
+			rw.Write([]byte(`[
+				{"tag_name": "v7.0.0-rc0", "prerelease": true},
+				{"tag_name": "v7.1.0-draft", "draft": true},
+				{"tag_name": "v6.0.4"}
+			]`))
+		case "/github.com/cosmos/gaia/v6/@v/list":
+			rw.Write([]byte("v6.0.0\nv6.0.1\nv6.0.4\n"))
+		case "/cosmos/gaia/v6.0.0/go.mod":
+			rw.Write(testdataGoMod)
+		default:
+			http.NotFound(rw, req)
+		}
+	}))
+	defer cst.Close()
+
+	destURL, err := url.Parse(cst.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	art := &alwaysToURLRoundTripper{next: cst.Client(), destURL: destURL}
+	fr := newFetcher(art, WithReleaseDiscovery())
+
+	seed := ChainSchema{
+		ChainName:   "cosmoshub",
+		NetworkType: "mainnet",
+		Codebase:    &Codebase{GitRepoURL: "https://github.com/cosmos/gaia", RecommendedVersion: "v6.0.0"},
+	}
+	cs, _, err := fr.run(context.Background(), seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ReleaseSummary{
+		Sources:              []ReleaseSource{ReleaseSourceGit, ReleaseSourceGitHub, ReleaseSourceProxy},
+		Versions:             []string{"v7.0.0-rc0", "v6.0.4", "v6.0.1", "v6.0.0"},
+		NewestStable:         "v6.0.4",
+		NewestPrerelease:     "v7.0.0-rc0",
+		StableReleasesBehind: 2,
+		Lag:                  LagPatch,
+	}
+	if diff := cmp.Diff(cs.Releases, want); diff != "" {
+		t.Fatalf("Releases mismatch: got - want +\n%s", diff)
+	}
+
+	// A recommended_version that needs resolving to its v-prefixed tag
+	// still has its go.mod's module path looked up for the module proxy.
+	fr = newFetcher(art, WithReleaseDiscovery(ReleaseSourceProxy))
+	seed.Codebase.RecommendedVersion = "6.0.0"
+	cs, _, err = fr.run(context.Background(), seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs.Resolved == nil || cs.Resolved.Ref != "v6.0.0" {
+		t.Fatalf("Expected 6.0.0 to resolve to v6.0.0, got %#v", cs.Resolved)
+	}
+	if g, w := cs.Releases.Sources, []ReleaseSource{ReleaseSourceProxy}; !cmp.Equal(g, w) {
+		t.Errorf("Sources: got %q, want %q, errors: %q", g, w, cs.Releases.Errors)
+	}
+	seed.Codebase.RecommendedVersion = "v6.0.0"
+
+	// A failing source is reported without discarding the others.
+	fr = newFetcher(art, WithReleaseDiscovery(ReleaseSourceGit, ReleaseSourceProxy))
+	seed.Codebase.GitRepoURL = "https://github.com/cosmos/gaia-fork"
+	if _, _, err := fr.run(context.Background(), seed); err == nil {
+		t.Fatal("Expected an error as the go.mod doesn't exist")
+	}
+	rs := fr.retrieveReleases(context.Background(), &http.Client{Transport: art}, RepoID{Host: "github.com", Owner: "cosmos", Repo: "gaia-fork"}, &seed)
+	if len(rs.Sources) != 0 || len(rs.Errors) != 2 {
+		t.Fatalf("Expected both sources to fail, got %#v", rs)
+	}
+}
@@ -0,0 +1,169 @@
+package chainparse
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// RepoID is the canonical identity of a chain's git repository,
+// derived from the registry's git_repo by NormalizeRepoURL.
+type RepoID struct {
+	Host string `json:"host"`
+
+	// Owner is the user or organization owning the repository. For GitLab
+	// it includes any subgroups, e.g. "group/subgroup".
+	Owner string `json:"owner"`
+	Repo  string `json:"repo"`
+}
+
+// String returns the identity in the form "github.com/cosmos/gaia".
+func (id RepoID) String() string {
+	return id.Host + "/" + id.Owner + "/" + id.Repo
+}
+
+// URL returns the repository's canonical HTTPS URL, which is also its git remote.
+func (id RepoID) URL() string {
+	return "https://" + id.String()
+}
+
+// OrgRepo returns the path of the repository on its host, e.g. "/cosmos/gaia".
+func (id RepoID) OrgRepo() string {
+	return "/" + id.Owner + "/" + id.Repo
+}
+
+// Key returns the identity folded to lower case, since the supported hosts
+// treat owners and repositories case insensitively, so that the same
+// repository spelled differently by different chains is only fetched once.
+func (id RepoID) Key() string {
+	return strings.ToLower(id.String())
+}
+
+// IsGitHub reports whether the repository is hosted on GitHub.
+func (id RepoID) IsGitHub() bool {
+	return id.Host == "github.com"
+}
+
+func (id RepoID) isGitLab() bool {
+	return id.Host == "gitlab.com" || strings.HasPrefix(id.Host, "gitlab.")
+}
+
+// rawFileURL returns the URL serving the raw contents of file at ref.
+func (id RepoID) rawFileURL(ref, file string) string {
+	var rawURL *url.URL
+	switch {
+	case id.IsGitHub():
+		rawURL = &url.URL{Scheme: "https", Host: "raw.githubusercontent.com", Path: id.OrgRepo() + "/" + ref + "/" + file}
+	case id.isGitLab():
+		rawURL = &url.URL{Scheme: "https", Host: id.Host, Path: id.OrgRepo() + "/-/raw/" + ref + "/" + file}
+	default:
+		// Bitbucket, Gitea and friends.
+		rawURL = &url.URL{Scheme: "https", Host: id.Host, Path: id.OrgRepo() + "/raw/" + ref + "/" + file}
+	}
+	return rawURL.String()
+}
+
+var reSCPLikeURL = regexp.MustCompile(`^(?:[\w.-]+@)?([\w.-]+\.[a-zA-Z]+):(.+)$`)
+
+// NormalizeRepoURL derives the canonical RepoID from a registry git_repo value.
+// Values are repaired where the intent is clear, with a warning describing every
+// repair: surrounding whitespace, SSH URLs such as git@github.com:org/repo.git,
+// missing or plain HTTP schemes, mixed case or "www." hosts, ".git" suffixes and
+// paths to somewhere within the repository such as /tree/main.
+func NormalizeRepoURL(gitRepo string) (id RepoID, warnings []string, err error) {
+	repair := func(format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf("git_repo %q: ", gitRepo)+fmt.Sprintf(format, args...))
+	}
+
+	s := strings.TrimSpace(gitRepo)
+	if s == "" {
+		return id, nil, fmt.Errorf("git_repo is empty")
+	}
+	if s != gitRepo {
+		repair("trimmed the surrounding whitespace")
+	}
+
+	switch {
+	case strings.HasPrefix(s, "ssh://"), strings.HasPrefix(s, "git+ssh://"):
+		u, err := url.Parse(s)
+		if err != nil {
+			return id, nil, err
+		}
+		s = "https://" + u.Hostname() + u.Path
+		repair("converted the SSH URL to HTTPS")
+	case !strings.Contains(s, "://"):
+		if m := reSCPLikeURL.FindStringSubmatch(s); m != nil {
+			s = "https://" + m[1] + "/" + strings.TrimPrefix(m[2], "/")
+			repair("converted the SSH URL to HTTPS")
+		} else {
+			s = "https://" + s
+			repair("added the missing https scheme")
+		}
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return id, nil, err
+	}
+	switch u.Scheme {
+	case "https":
+	case "http", "git":
+		repair("replaced the %s scheme with https", u.Scheme)
+	default:
+		return id, nil, fmt.Errorf("unsupported scheme %q in git_repo %q", u.Scheme, gitRepo)
+	}
+	if u.User != nil {
+		repair("dropped the credentials")
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		repair("dropped the query and fragment")
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host != u.Hostname() {
+		repair("lower cased the host")
+	}
+	if strings.HasPrefix(host, "www.") {
+		host = strings.TrimPrefix(host, "www.")
+		repair("dropped the www. prefix from the host")
+	}
+	if host == "" {
+		return id, nil, fmt.Errorf("git_repo %q has no host", gitRepo)
+	}
+	id.Host = host
+
+	var segments []string
+	for _, seg := range strings.Split(u.Path, "/") {
+		if seg != "" {
+			segments = append(segments, seg)
+		}
+	}
+	if len(segments) < 2 {
+		return id, nil, fmt.Errorf("git_repo %q has no owner and repository", gitRepo)
+	}
+
+	// GitLab repositories can be nested within subgroups, and paths
+	// within the repository are separated from it by "/-/".
+	n := 2
+	if id.isGitLab() {
+		for n = 0; n < len(segments) && segments[n] != "-"; n++ {
+		}
+	}
+	if n < 2 {
+		return id, nil, fmt.Errorf("git_repo %q has no owner and repository", gitRepo)
+	}
+	if n < len(segments) {
+		repair("dropped %q from the path", "/"+strings.Join(segments[n:], "/"))
+	}
+	id.Owner = strings.Join(segments[:n-1], "/")
+	id.Repo = segments[n-1]
+	if strings.HasSuffix(id.Repo, ".git") {
+		id.Repo = strings.TrimSuffix(id.Repo, ".git")
+		repair("dropped the .git suffix")
+	}
+	if id.Owner == "" || id.Repo == "" {
+		return id, nil, fmt.Errorf("git_repo %q has no owner and repository", gitRepo)
+	}
+	return id, warnings, nil
+}
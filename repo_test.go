@@ -0,0 +1,65 @@
+package chainparse
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNormalizeRepoURL(t *testing.T) {
+	tests := []struct {
+		in           string
+		want         RepoID
+		wantWarnings int
+	}{
+		{"https://github.com/cosmos/gaia", RepoID{"github.com", "cosmos", "gaia"}, 0},
+		{"https://github.com/Agoric/ag0/", RepoID{"github.com", "Agoric", "ag0"}, 0},
+		{"https://github.com/cosmos/gaia.git", RepoID{"github.com", "cosmos", "gaia"}, 1},
+		{"git@github.com:cosmos/gaia.git", RepoID{"github.com", "cosmos", "gaia"}, 2},
+		{"ssh://git@github.com/cosmos/gaia", RepoID{"github.com", "cosmos", "gaia"}, 1},
+		{"https://github.com/cosmos/gaia/tree/main", RepoID{"github.com", "cosmos", "gaia"}, 1},
+		{"https://GitHub.com/cosmos/gaia", RepoID{"github.com", "cosmos", "gaia"}, 1},
+		{"https://www.github.com/cosmos/gaia", RepoID{"github.com", "cosmos", "gaia"}, 1},
+		{"  https://github.com/cosmos/gaia\n", RepoID{"github.com", "cosmos", "gaia"}, 1},
+		{"github.com/cosmos/gaia", RepoID{"github.com", "cosmos", "gaia"}, 1},
+		{"http://github.com/cosmos/gaia", RepoID{"github.com", "cosmos", "gaia"}, 1},
+		{"https://gitlab.com/thorchain/thornode", RepoID{"gitlab.com", "thorchain", "thornode"}, 0},
+		{"https://gitlab.com/group/subgroup/project/-/tree/develop", RepoID{"gitlab.com", "group/subgroup", "project"}, 1},
+	}
+
+	for _, tt := range tests {
+		got, warnings, err := NormalizeRepoURL(tt.in)
+		if err != nil {
+			t.Errorf("%q: %v", tt.in, err)
+			continue
+		}
+		if diff := cmp.Diff(got, tt.want); diff != "" {
+			t.Errorf("%q: mismatch: got - want +\n%s", tt.in, diff)
+		}
+		if g, w := len(warnings), tt.wantWarnings; g != w {
+			t.Errorf("%q: warnings: got %d %q, want %d", tt.in, g, warnings, w)
+		}
+	}
+
+	for _, in := range []string{"", "https://github.com/cosmos", "https://github.com/", "ftp://github.com/cosmos/gaia"} {
+		if id, _, err := NormalizeRepoURL(in); err == nil {
+			t.Errorf("%q: expected an error, got %#v", in, id)
+		}
+	}
+}
+
+func TestRawGoModURL(t *testing.T) {
+	tests := []struct {
+		repo RepoID
+		want string
+	}{
+		{RepoID{"github.com", "Agoric", "ag0"}, "https://raw.githubusercontent.com/Agoric/ag0/agoric-3.1/go.mod"},
+		{RepoID{"gitlab.com", "thorchain", "thornode"}, "https://gitlab.com/thorchain/thornode/-/raw/agoric-3.1/go.mod"},
+		{RepoID{"bitbucket.org", "acme", "chain"}, "https://bitbucket.org/acme/chain/raw/agoric-3.1/go.mod"},
+	}
+	for _, tt := range tests {
+		if g, w := rawGoModURL(tt.repo, "agoric-3.1"), tt.want; g != w {
+			t.Errorf("%s: got %q, want %q", tt.repo, g, w)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package chainparse
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/semver"
+)
+
+// The upstream module paths of the tracked dependencies, replacing any
+// of them with a module path other than these is considered a fork.
+var upstreamModulePaths = map[string]bool{
+	"github.com/cosmos/cosmos-sdk":     true,
+	"github.com/tendermint/tendermint": true,
+	"github.com/cometbft/cometbft":     true,
+	"github.com/cosmos/ibc-go":         true,
+}
+
+// splitDependency splits a dependency version as reported in ChainSchema, such as
+// "v0.44.2-alpha.agoric.gaiad.1@github.com/agoric-labs/cosmos-sdk", into the
+// version and the module path it was replaced with, if any.
+func splitDependency(dep string) (version, replacement string) {
+	if i := strings.IndexByte(dep, '@'); i >= 0 {
+		return dep[:i], dep[i+1:]
+	}
+	return dep, ""
+}
+
+// isForkedDependency reports whether dep was replaced with a module other than upstream's.
+func isForkedDependency(dep string) bool {
+	_, replacement := splitDependency(dep)
+	if replacement == "" {
+		return false
+	}
+	// Strip any major version suffix such as the /v7 of ibc-go.
+	if i := strings.LastIndexByte(replacement, '/'); i >= 0 && semver.IsValid(replacement[i+1:]) {
+		replacement = replacement[:i]
+	}
+	return !upstreamModulePaths[replacement]
+}
+
+// ReportDependency is one of a chain's tracked dependencies in a Report.
+type ReportDependency struct {
+	Version string
+
+	// ReplacedBy is the module path that the dependency was replaced with, if any.
+	ReplacedBy string
+
+	// Forked is set if ReplacedBy isn't the upstream module.
+	Forked bool
+
+	// Outdated is set if the version's major.minor is older than the
+	// newest major.minor of the dependency amongst the reported chains.
+	Outdated bool
+}
+
+func (rd ReportDependency) String() string {
+	if rd.ReplacedBy == "" {
+		return rd.Version
+	}
+	return rd.Version + "@" + rd.ReplacedBy
+}
+
+// ReportRow is a chain in a Report.
+type ReportRow struct {
+	Chain *ChainSchema
+
+	CosmosSDK  ReportDependency
+	Tendermint ReportDependency
+	IBC        ReportDependency
+
+	// Lag is how far recommended_version lags behind the newest release,
+	// see ReleaseSummary.Lag, or "" if releases weren't discovered.
+	Lag string
+}
+
+// Outdated reports whether any of the row's dependencies is outdated.
+func (row *ReportRow) Outdated() bool {
+	return row.CosmosSDK.Outdated || row.Tendermint.Outdated || row.IBC.Outdated
+}
+
+// Forked reports whether any of the row's dependencies is forked.
+func (row *ReportRow) Forked() bool {
+	return row.CosmosSDK.Forked || row.Tendermint.Forked || row.IBC.Forked
+}
+
+// Report is the model that the Markdown and HTML reports are rendered from.
+type Report struct {
+	GeneratedAt time.Time
+	Summary     string
+
+	Mainnets []*ReportRow
+
+	// Testnets holds every chain whose network_type isn't mainnet.
+	Testnets []*ReportRow
+	Failures []*Outcome
+
+	// Newest maps every tracked dependency, cosmos-sdk, tendermint or ibc-go,
+	// to the newest major.minor version of it amongst the reported chains.
+	Newest map[string]string
+}
+
+// NewReport builds the Report for res.
+func NewReport(res *Result, generatedAt time.Time) *Report {
+	rep := &Report{
+		GeneratedAt: generatedAt.UTC(),
+		Summary:     res.Summary(),
+		Failures:    res.Failures(),
+		Newest:      make(map[string]string),
+	}
+
+	deps := func(cs *ChainSchema) map[string]string {
+		return map[string]string{
+			moduleCosmosSDK:  cs.CosmosSDKVersion,
+			moduleTendermint: cs.TendermintVersion,
+			moduleIBC:        cs.IBCVersion,
+		}
+	}
+	for _, cs := range res.Chains {
+		for module, dep := range deps(cs) {
+			vers, _ := splitDependency(dep)
+			if mm := semver.MajorMinor(vers); mm != "" && semver.Compare(mm, rep.Newest[module]) > 0 {
+				rep.Newest[module] = mm
+			}
+		}
+	}
+
+	for _, cs := range res.Chains {
+		dependency := func(module, dep string) ReportDependency {
+			vers, replacement := splitDependency(dep)
+			mm := semver.MajorMinor(vers)
+			return ReportDependency{
+				Version:    vers,
+				ReplacedBy: replacement,
+				Forked:     isForkedDependency(dep),
+				Outdated:   mm != "" && semver.Compare(mm, rep.Newest[module]) < 0,
+			}
+		}
+		row := &ReportRow{
+			Chain:      cs,
+			CosmosSDK:  dependency(moduleCosmosSDK, cs.CosmosSDKVersion),
+			Tendermint: dependency(moduleTendermint, cs.TendermintVersion),
+			IBC:        dependency(moduleIBC, cs.IBCVersion),
+		}
+		if cs.Releases != nil {
+			row.Lag = cs.Releases.Lag
+		}
+		if cs.NetworkType == "mainnet" {
+			rep.Mainnets = append(rep.Mainnets, row)
+		} else {
+			rep.Testnets = append(rep.Testnets, row)
+		}
+	}
+	return rep
+}
+
+var markdownCellEscaper = strings.NewReplacer("|", `\|`, "<", "&lt;", ">", "&gt;")
+
+// markdownCell escapes s for use within a Markdown table cell, in which
+// pipes would end the cell and tags would be rendered as HTML.
+func markdownCell(s string) string {
+	s = markdownCellEscaper.Replace(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func markdownDependency(rd ReportDependency) string {
+	if rd.Version == "" {
+		return ""
+	}
+	cell := "`" + rd.Version + "`"
+	if rd.Forked {
+		cell += " 🍴 " + markdownCell(rd.ReplacedBy)
+	}
+	if rd.Outdated {
+		cell = "⚠️ " + cell
+	}
+	return cell
+}
+
+// WriteMarkdownReport writes the report of res as Markdown tables,
+// ready to be pasted into governance posts and wikis.
+func WriteMarkdownReport(w io.Writer, res *Result, generatedAt time.Time) error {
+	rep := NewReport(res, generatedAt)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Cosmos chain status\n\nGenerated at %s: %s.\n\n", rep.GeneratedAt.Format(time.RFC3339), rep.Summary)
+	b.WriteString("⚠️ marks a dependency older than the newest release line in use by another chain, 🍴 a forked one.\n")
+
+	for _, section := range []struct {
+		title string
+		rows  []*ReportRow
+	}{{"Mainnets", rep.Mainnets}, {"Testnets", rep.Testnets}} {
+		fmt.Fprintf(&b, "\n## %s\n\n", section.title)
+		if len(section.rows) == 0 {
+			b.WriteString("None.\n")
+			continue
+		}
+		b.WriteString("| Chain | Repository | Version | Cosmos SDK | Tendermint | IBC | Lag |\n")
+		b.WriteString("| --- | --- | --- | --- | --- | --- | --- |\n")
+		for _, row := range section.rows {
+			var repo, version string
+			if cb := row.Chain.Codebase; cb != nil {
+				repo = markdownCell(cb.GitRepoURL)
+				version = markdownCell(cb.RecommendedVersion)
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s |\n",
+				markdownCell(row.Chain.PrettyName), repo, version,
+				markdownDependency(row.CosmosSDK), markdownDependency(row.Tendermint), markdownDependency(row.IBC),
+				row.Lag)
+		}
+	}
+
+	b.WriteString("\n## Failures\n\n")
+	if len(rep.Failures) == 0 {
+		b.WriteString("None.\n")
+	} else {
+		b.WriteString("| Chain | Category | HTTP status | Message |\n")
+		b.WriteString("| --- | --- | --- | --- |\n")
+		for _, out := range rep.Failures {
+			status := ""
+			if out.HTTPStatus != 0 {
+				status = fmt.Sprint(out.HTTPStatus)
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", markdownCell(out.ChainName), out.Category, status, markdownCell(out.Message))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+//go:embed templates/report.html
+var reportHTML string
+
+var reportHTMLTmpl = template.Must(template.New("report.html").Parse(reportHTML))
+
+// WriteHTMLReport writes the report of res as a self-contained HTML page
+// whose tables can be sorted by clicking on their headers and filtered.
+func WriteHTMLReport(w io.Writer, res *Result, generatedAt time.Time) error {
+	return reportHTMLTmpl.Execute(w, NewReport(res, generatedAt))
+}
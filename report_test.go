@@ -0,0 +1,128 @@
+package chainparse
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var reportResult = &Result{
+	Chains: []*ChainSchema{
+		{
+			ChainName:         "agoric",
+			NetworkType:       "mainnet",
+			PrettyName:        "Agoric",
+			Codebase:          &Codebase{GitRepoURL: "https://github.com/Agoric/ag0", RecommendedVersion: "agoric-3.1"},
+			CosmosSDKVersion:  "v0.44.2-alpha.agoric.gaiad.1@github.com/agoric-labs/cosmos-sdk",
+			TendermintVersion: "v0.34.13@github.com/tendermint/tendermint",
+			IBCVersion:        "v1.2.0",
+		},
+		{
+			ChainName:         "cosmoshub",
+			NetworkType:       "mainnet",
+			PrettyName:        "Cosmos | Hub",
+			Codebase:          &Codebase{GitRepoURL: "https://github.com/cosmos/gaia", RecommendedVersion: "v7.0.0"},
+			CosmosSDKVersion:  "v0.45.4",
+			TendermintVersion: "v0.34.19",
+			IBCVersion:        "v3.0.0@github.com/cosmos/ibc-go/v3",
+			Releases:          &ReleaseSummary{Lag: LagMinor},
+		},
+		{
+			ChainName:        "theta",
+			NetworkType:      "testnet",
+			PrettyName:       "<Theta>",
+			CosmosSDKVersion: "v0.45.4",
+		},
+	},
+	Outcomes: []*Outcome{
+		{ChainName: "agoric", Status: OutcomeOK},
+		{ChainName: "aioz", Status: OutcomeFailed, Category: CategoryHTTP, HTTPStatus: 404, Message: `HTTP request failed with status: "404 Not Found"`},
+		{ChainName: "cosmoshub", Status: OutcomeOK},
+		{ChainName: "theta", Status: OutcomeOK},
+	},
+}
+
+var reportTime = time.Date(2022, time.October, 1, 12, 0, 0, 0, time.UTC)
+
+func TestNewReport(t *testing.T) {
+	rep := NewReport(reportResult, reportTime)
+	if g, w := len(rep.Mainnets), 2; g != w {
+		t.Fatalf("Mainnets: got %d, want %d", g, w)
+	}
+	if g, w := len(rep.Testnets), 1; g != w {
+		t.Fatalf("Testnets: got %d, want %d", g, w)
+	}
+
+	agoric, hub := rep.Mainnets[0], rep.Mainnets[1]
+	want := ReportDependency{
+		Version:    "v0.44.2-alpha.agoric.gaiad.1",
+		ReplacedBy: "github.com/agoric-labs/cosmos-sdk",
+		Forked:     true,
+		Outdated:   true,
+	}
+	if diff := cmp.Diff(agoric.CosmosSDK, want); diff != "" {
+		t.Errorf("Agoric Cosmos SDK mismatch: got - want +\n%s", diff)
+	}
+	if agoric.Tendermint.Forked || agoric.Tendermint.Outdated {
+		t.Errorf("Tendermint replaced by upstream itself isn't a fork: %#v", agoric.Tendermint)
+	}
+	if hub.IBC.Forked || hub.Forked() || hub.Outdated() {
+		t.Errorf("The hub should be neither outdated nor forked: %#v", hub)
+	}
+	if g, w := hub.Lag, LagMinor; g != w {
+		t.Errorf("Lag: got %q, want %q", g, w)
+	}
+}
+
+func TestWriteMarkdownReport(t *testing.T) {
+	var b strings.Builder
+	if err := WriteMarkdownReport(&b, reportResult, reportTime); err != nil {
+		t.Fatal(err)
+	}
+	want := "# Cosmos chain status\n\n" +
+		"Generated at 2022-10-01T12:00:00Z: 3 ok, 0 skipped, 1 failed.\n\n" +
+		"⚠️ marks a dependency older than the newest release line in use by another chain, 🍴 a forked one.\n\n" +
+		"## Mainnets\n\n" +
+		"| Chain | Repository | Version | Cosmos SDK | Tendermint | IBC | Lag |\n" +
+		"| --- | --- | --- | --- | --- | --- | --- |\n" +
+		"| Agoric | https://github.com/Agoric/ag0 | agoric-3.1 | ⚠️ `v0.44.2-alpha.agoric.gaiad.1` 🍴 github.com/agoric-labs/cosmos-sdk | `v0.34.13` | ⚠️ `v1.2.0` |  |\n" +
+		"| Cosmos \\| Hub | https://github.com/cosmos/gaia | v7.0.0 | `v0.45.4` | `v0.34.19` | `v3.0.0` | minor |\n\n" +
+		"## Testnets\n\n" +
+		"| Chain | Repository | Version | Cosmos SDK | Tendermint | IBC | Lag |\n" +
+		"| --- | --- | --- | --- | --- | --- | --- |\n" +
+		"| &lt;Theta&gt; |  |  | `v0.45.4` |  |  |  |\n\n" +
+		"## Failures\n\n" +
+		"| Chain | Category | HTTP status | Message |\n" +
+		"| --- | --- | --- | --- |\n" +
+		"| aioz | http | 404 | HTTP request failed with status: \"404 Not Found\" |\n"
+	if diff := cmp.Diff(b.String(), want); diff != "" {
+		t.Errorf("Markdown mismatch: got - want +\n%s", diff)
+	}
+}
+
+func TestWriteHTMLReport(t *testing.T) {
+	var b strings.Builder
+	if err := WriteHTMLReport(&b, reportResult, reportTime); err != nil {
+		t.Fatal(err)
+	}
+	html := b.String()
+	for _, want := range []string{
+		`<h2 id="mainnets">Mainnets</h2>`,
+		`<h2 id="testnets">Testnets</h2>`,
+		`<h2 id="failures">Failures</h2>`,
+		`<td class="outdated forked" data-sort="v0.44.2-alpha.agoric.gaiad.1"><code>v0.44.2-alpha.agoric.gaiad.1</code><br><small>github.com/agoric-labs/cosmos-sdk</small></td>`,
+		`<a href="https://github.com/cosmos/gaia">https://github.com/cosmos/gaia</a>`,
+		`<td>&lt;Theta&gt;</td>`,
+		`<td>404</td>`,
+		`table.sortable`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML report is missing %s", want)
+		}
+	}
+	if strings.Contains(html, "<Theta>") {
+		t.Error("The pretty name should have been escaped")
+	}
+}
@@ -0,0 +1,221 @@
+package chainparse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// RefResolution records how a registry version was resolved when
+// it couldn't be used literally as a git ref to fetch go.mod from.
+type RefResolution struct {
+	// Ref is the git ref that the version resolved to.
+	Ref string `json:"ref,omitempty"`
+
+	// Source is "git" if the version was matched against the repository's
+	// refs or releases, and "proxy" if go.mod came from the module proxy.
+	Source string `json:"source"`
+
+	// Module is the module@version that the module proxy served.
+	Module string `json:"module,omitempty"`
+}
+
+const (
+	resolvedFromGit   = "git"
+	resolvedFromProxy = "proxy"
+)
+
+// refCandidates returns the spellings of version to look for amongst a
+// repository's refs, most likely first: a version of "1.2.0" is commonly
+// tagged as "v1.2.0" and vice versa.
+func refCandidates(version string) []string {
+	version = strings.TrimPrefix(version, "refs/tags/")
+	version = strings.TrimPrefix(version, "refs/heads/")
+	if strings.HasPrefix(version, "v") {
+		return []string{version, version[1:]}
+	}
+	return []string{version, "v" + version}
+}
+
+func isCommitHash(s string) bool {
+	if len(s) < 7 || len(s) > 40 {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveRef resolves version to a ref of repo. The
+// candidates are checked against the advertised branches and tags, where
+// branches may contain slashes, then abbreviated commit hashes are expanded
+// and lastly GitHub release names are looked up. If nothing matches, then
+// version is returned unchanged.
+func (fr *fetcher) resolveRef(ctx context.Context, client *http.Client, repo RepoID, version string) (string, error) {
+	refs, err := fr.lsRemote(ctx, repo)
+	if err != nil {
+		return version, err
+	}
+
+	candidates := refCandidates(version)
+	for _, cand := range candidates {
+		if _, ok := refs.Refs["refs/tags/"+cand]; ok {
+			return cand, nil
+		}
+		if _, ok := refs.Refs["refs/heads/"+cand]; ok {
+			return cand, nil
+		}
+	}
+
+	if hash := strings.ToLower(version); isCommitHash(hash) {
+		for _, oids := range []map[string]string{refs.Refs, refs.Peeled} {
+			for _, oid := range oids {
+				if strings.HasPrefix(oid, hash) {
+					return oid, nil
+				}
+			}
+		}
+		return hash, nil
+	}
+
+	if releases, err := fr.githubReleaseList(ctx, client, repo); err == nil {
+		for _, release := range releases {
+			if release.Name != "" && strings.EqualFold(strings.TrimSpace(release.Name), version) {
+				return release.TagName, nil
+			}
+		}
+	}
+	return version, nil
+}
+
+// retrieveVersion analyses the go.mod at one of the registry's versions for
+// a chain. If the version doesn't work as a literal git ref, it is resolved
+// against the repository's refs and, failing that, looked up via the module
+// proxy. A non-nil RefResolution is returned if it wasn't used literally.
+func (fr *fetcher) retrieveVersion(ctx context.Context, client *http.Client, repo RepoID, version string, seed ChainSchema) (*ChainSchema, *RefResolution, error) {
+	if modBlob, ok := fr.cachedGoMod(repo, version); ok {
+		cs, err := fr.parseModFile(modBlob, seed)
+		if err != nil {
+			err = &FetchError{Category: CategoryParse, URL: rawGoModURL(repo, version), Err: err}
+		}
+		return cs, nil, err
+	}
+
+	// Most versions are literal refs, so only list the repository's refs
+	// once fetching go.mod at the literal version has failed.
+	cs, err := fr.retrieveModFile(ctx, client, modCacheKey(repo, version), rawGoModURL(repo, version), seed)
+	var fe *FetchError
+	if err == nil || (errors.As(err, &fe) && fe.Category == CategoryParse) {
+		// A go.mod that can't be parsed won't be fixed by looking elsewhere.
+		return cs, nil, err
+	}
+	if ref, rerr := fr.resolveRef(ctx, client, repo, version); rerr == nil && ref != version {
+		cs, rerr := fr.retrieveModFile(ctx, client, modCacheKey(repo, ref), rawGoModURL(repo, ref), seed)
+		if rerr == nil {
+			return cs, &RefResolution{Ref: ref, Source: resolvedFromGit}, nil
+		}
+	}
+
+	// The git lookups failed, so fall back to the module proxy
+	// but still report the original failure if that fails too.
+	pcs, modVers, perr := fr.retrieveProxyModFile(ctx, client, repo, version, seed)
+	if perr != nil {
+		return nil, nil, err
+	}
+	return pcs, &RefResolution{Source: resolvedFromProxy, Module: modVers.String()}, nil
+}
+
+// proxyModulePaths returns the module paths that repo could be
+// serving version of, e.g. github.com/cosmos/gaia/v6 for v6.0.0.
+func proxyModulePaths(repo RepoID, version string) []string {
+	modPath := repo.String()
+	if err := module.CheckPath(modPath); err != nil {
+		return nil
+	}
+	if vers := tagSemver(version); vers != "" {
+		if major := semver.Major(vers); major != "v0" && major != "v1" {
+			return []string{modPath + "/" + major, modPath}
+		}
+	}
+	return []string{modPath}
+}
+
+// retrieveProxyModFile fetches the go.mod at version from the module proxy,
+// which also resolves branch names and commit hashes to pseudo-versions.
+func (fr *fetcher) retrieveProxyModFile(ctx context.Context, client *http.Client, repo RepoID, version string, seed ChainSchema) (*ChainSchema, module.Version, error) {
+	query := version
+	if vers := tagSemver(version); vers != "" {
+		query = vers
+	}
+
+	var lastErr error = fmt.Errorf("no module path can be derived from %q", repo)
+	for _, modPath := range proxyModulePaths(repo, version) {
+		escPath, err := module.EscapePath(modPath)
+		if err != nil {
+			return nil, module.Version{}, err
+		}
+		escQuery, err := module.EscapeVersion(query)
+		if err != nil {
+			return nil, module.Version{}, err
+		}
+
+		infoBlob, err := fr.proxyGet(ctx, client, goProxyURL+"/"+escPath+"/@v/"+escQuery+".info")
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		info := new(struct{ Version string })
+		if err := json.Unmarshal(infoBlob, info); err != nil {
+			lastErr = err
+			continue
+		}
+		escVers, err := module.EscapeVersion(info.Version)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		modURL := goProxyURL + "/" + escPath + "/@v/" + escVers + ".mod"
+		cs, err := fr.retrieveModFile(ctx, client, modURL, modURL, seed)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return cs, module.Version{Path: modPath, Version: info.Version}, nil
+	}
+	return nil, module.Version{}, lastErr
+}
+
+func (fr *fetcher) proxyGet(ctx context.Context, client *http.Client, uri string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, &FetchError{Category: CategoryNetwork, URL: uri, Err: err}
+	}
+	blob, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, &FetchError{Category: CategoryNetwork, URL: uri, Err: err}
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, &FetchError{
+			Category:   CategoryHTTP,
+			URL:        uri,
+			HTTPStatus: res.StatusCode,
+			Err:        fmt.Errorf("HTTP request failed with status: %q", res.Status),
+		}
+	}
+	return blob, nil
+}
@@ -0,0 +1,103 @@
+package chainparse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRefCandidates(t *testing.T) {
+	if diff := cmp.Diff(refCandidates("1.2.0"), []string{"1.2.0", "v1.2.0"}); diff != "" {
+		t.Errorf("1.2.0: got - want +\n%s", diff)
+	}
+	if diff := cmp.Diff(refCandidates("refs/tags/v1.2.0"), []string{"v1.2.0", "1.2.0"}); diff != "" {
+		t.Errorf("refs/tags/v1.2.0: got - want +\n%s", diff)
+	}
+	for s, want := range map[string]bool{"abc1234": true, oidMain: true, "abc12": false, "v1.2.0": false, "deadbeefX": false} {
+		if got := isCommitHash(s); got != want {
+			t.Errorf("isCommitHash(%q): got %t, want %t", s, got, want)
+		}
+	}
+}
+
+func TestRetrieveVersionResolution(t *testing.T) {
+	cst := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/acme/chain/info/refs":
+			rw.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
+			rw.Write([]byte(pktLines(
+				"# service=git-upload-pack\n",
+				"",
+				oidMain+" HEAD\x00symref=HEAD:refs/heads/main\n",
+				oidMain+" refs/heads/main\n",
+				oidMain+" refs/heads/release/v2.x\n",
+				oidTag+" refs/tags/v1.2.0\n",
+				"",
+			)))
+		case "/repos/acme/chain/releases":
+			rw.Write([]byte(`[{"tag_name": "v1.2.0", "name": "Mainnet Upgrade"}]`))
+		case "/acme/chain/v1.2.0/go.mod", "/acme/chain/release/v2.x/go.mod", "/acme/chain/" + oidMain + "/go.mod":
+			rw.Write(testdataGoMod)
+		case "/github.com/acme/chain/v9/@v/v9.9.9.info":
+			rw.Write([]byte(`{"Version": "v9.9.9", "Time": "2022-10-01T00:00:00Z"}`))
+		case "/github.com/acme/chain/v9/@v/v9.9.9.mod":
+			rw.Write(testdataLatestGoMod)
+		default:
+			http.NotFound(rw, req)
+		}
+	}))
+	defer cst.Close()
+
+	destURL, err := url.Parse(cst.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	art := &alwaysToURLRoundTripper{next: cst.Client(), destURL: destURL}
+	client := &http.Client{Transport: art}
+	seed := ChainSchema{
+		ChainName:   "acme",
+		NetworkType: "mainnet",
+		Codebase:    &Codebase{GitRepoURL: "https://github.com/acme/chain"},
+	}
+
+	tests := []struct {
+		version string
+		want    *RefResolution
+		wantTM  string
+	}{
+		{"v1.2.0", nil, "v0.34.13@github.com/tendermint/tendermint"},
+		{"1.2.0", &RefResolution{Ref: "v1.2.0", Source: "git"}, "v0.34.13@github.com/tendermint/tendermint"},
+		{"refs/heads/release/v2.x", &RefResolution{Ref: "release/v2.x", Source: "git"}, "v0.34.13@github.com/tendermint/tendermint"},
+		{"1111111", &RefResolution{Ref: oidMain, Source: "git"}, "v0.34.13@github.com/tendermint/tendermint"},
+		{"Mainnet Upgrade", &RefResolution{Ref: "v1.2.0", Source: "git"}, "v0.34.13@github.com/tendermint/tendermint"},
+		{"v9.9.9", &RefResolution{Source: "proxy", Module: "github.com/acme/chain/v9@v9.9.9"}, "v0.37.13@github.com/tendermint/tendermint"},
+	}
+
+	ctx := context.Background()
+	fr := newFetcher(art)
+	acme := RepoID{Host: "github.com", Owner: "acme", Repo: "chain"}
+	for _, tt := range tests {
+		cs, resolved, err := fr.retrieveVersion(ctx, client, acme, tt.version, seed)
+		if err != nil {
+			t.Errorf("%q: %v", tt.version, err)
+			continue
+		}
+		if diff := cmp.Diff(resolved, tt.want); diff != "" {
+			t.Errorf("%q: resolution mismatch: got - want +\n%s", tt.version, diff)
+		}
+		if g, w := cs.TendermintVersion, tt.wantTM; g != w {
+			t.Errorf("%q: Tendermint version: got %q, want %q", tt.version, g, w)
+		}
+	}
+
+	// The original failure is what gets reported when nothing resolves.
+	_, _, err = fr.retrieveVersion(ctx, client, acme, "v0.0.1", seed)
+	fe, ok := err.(*FetchError)
+	if !ok || fe.HTTPStatus != http.StatusNotFound || fe.URL != "https://raw.githubusercontent.com/acme/chain/v0.0.1/go.mod" {
+		t.Fatalf("Expected the literal version's 404, got %#v", err)
+	}
+}
@@ -0,0 +1,392 @@
+package chainparse
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/module"
+)
+
+// errNoGoModule is returned when generating the SBOM of a chain that wasn't
+// analysed with WithGoModules or WithGoSums.
+var errNoGoModule = errors.New("the chain's go.mod wasn't retained, analyse it with WithGoSums or WithGoModules")
+
+// sbomModule is a module of a chain's build list once replace directives are applied.
+type sbomModule struct {
+	Path, Version string
+
+	// Replaced is the module that the replace directive replaced, if any.
+	Replaced *module.Version
+
+	// LocalPath is the directory the module was replaced with, if any,
+	// in which case Path and Version are those of the module replaced.
+	LocalPath string
+
+	Indirect bool
+
+	// Sum is the "h1:" hash of the module from go.sum, if any. It's the hash
+	// of the module's file tree rather than of any artifact, hence it's
+	// recorded as is instead of as a SHA-256 of the module's zip file.
+	Sum string
+}
+
+// purl returns the package URL of the module, as per
+// https://github.com/package-url/purl-spec/blob/master/PURL-TYPES.rst#golang.
+func (sm *sbomModule) purl() string {
+	return goPURL(sm.Path, sm.Version)
+}
+
+func goPURL(path, version string) string {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		segments[i] = purlEscape(seg)
+	}
+	purl := "pkg:golang/" + strings.Join(segments, "/")
+	if version != "" {
+		purl += "@" + purlEscape(version)
+	}
+	return purl
+}
+
+// purlEscape percent-encodes everything but the unreserved characters.
+func purlEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("-._~", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// sbomModules returns the modules that gm requires, with its replace
+// directives applied, in the order of its require directives.
+func sbomModules(gm *GoModule) []*sbomModule {
+	var mods []*sbomModule
+	for _, req := range gm.Requires {
+		sm := &sbomModule{Path: req.Path, Version: req.Version, Indirect: req.Indirect}
+		// A replace directive for a specific version wins over one for all versions.
+		var rep *ModuleReplace
+		for _, r := range gm.Replaces {
+			if r.OldPath == req.Path && (r.OldVersion == req.Version || r.OldVersion == "" && rep == nil) {
+				rep = r
+			}
+		}
+		if rep != nil {
+			if rep.NewVersion == "" {
+				sm.LocalPath = rep.NewPath
+			} else {
+				sm.Replaced = &module.Version{Path: req.Path, Version: req.Version}
+				sm.Path, sm.Version = rep.NewPath, rep.NewVersion
+			}
+		}
+		if sm.LocalPath == "" {
+			sm.Sum = gm.Sums[sm.Path+"@"+sm.Version]
+		}
+		mods = append(mods, sm)
+	}
+	return mods
+}
+
+// sbomUUID returns a name based UUID, so that the same chain analysed at the
+// same time always gets the same serial number.
+func sbomUUID(name string) string {
+	sum := sha1.Sum([]byte(name))
+	sum[6] = sum[6]&0x0f | 0x50 // version 5
+	sum[8] = sum[8]&0x3f | 0x80 // RFC 4122 variant
+	h := hex.EncodeToString(sum[:16])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+type cdxBOM struct {
+	BOMFormat    string           `json:"bomFormat"`
+	SpecVersion  string           `json:"specVersion"`
+	SerialNumber string           `json:"serialNumber"`
+	Version      int              `json:"version"`
+	Metadata     cdxMetadata      `json:"metadata"`
+	Components   []*cdxComponent  `json:"components"`
+	Dependencies []*cdxDependency `json:"dependencies"`
+}
+
+type cdxMetadata struct {
+	Timestamp string        `json:"timestamp"`
+	Tools     []cdxTool     `json:"tools"`
+	Component *cdxComponent `json:"component"`
+}
+
+type cdxTool struct {
+	Name string `json:"name"`
+}
+
+type cdxComponent struct {
+	Type       string         `json:"type"`
+	BOMRef     string         `json:"bom-ref,omitempty"`
+	Name       string         `json:"name"`
+	Version    string         `json:"version,omitempty"`
+	PURL       string         `json:"purl,omitempty"`
+	Pedigree   *cdxPedigree   `json:"pedigree,omitempty"`
+	Properties []*cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxPedigree struct {
+	Ancestors []*cdxComponent `json:"ancestors"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// WriteCycloneDX writes the CycloneDX 1.5 JSON SBOM of the modules that cs is
+// built with at its recommended version. Modules replaced with another module
+// have the module they replace as their pedigree's ancestor, and the go.sum
+// hashes are recorded as the go.sum:h1 property if it was retrieved with WithGoSums.
+func WriteCycloneDX(w io.Writer, cs *ChainSchema, generatedAt time.Time) error {
+	gm := cs.GoModule
+	if gm == nil {
+		return errNoGoModule
+	}
+	version := ""
+	if cs.Codebase != nil {
+		version = cs.Codebase.RecommendedVersion
+	}
+	root := &cdxComponent{
+		Type:    "application",
+		BOMRef:  goPURL(gm.Path, version),
+		Name:    gm.Path,
+		Version: version,
+		PURL:    goPURL(gm.Path, version),
+		Properties: []*cdxProperty{
+			{Name: "chainparse:chain_name", Value: cs.ChainName},
+		},
+	}
+	if gm.GoVersion != "" {
+		root.Properties = append(root.Properties, &cdxProperty{Name: "chainparse:go_version", Value: gm.GoVersion})
+	}
+	bom := &cdxBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + sbomUUID(root.PURL+" "+generatedAt.UTC().Format(time.RFC3339)),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: generatedAt.UTC().Format(time.RFC3339),
+			Tools:     []cdxTool{{Name: "chainparse"}},
+			Component: root,
+		},
+		Components: []*cdxComponent{},
+	}
+
+	rootDeps := &cdxDependency{Ref: root.BOMRef, DependsOn: []string{}}
+	for _, sm := range sbomModules(gm) {
+		c := &cdxComponent{
+			Type:    "library",
+			BOMRef:  sm.purl(),
+			Name:    sm.Path,
+			Version: sm.Version,
+			PURL:    sm.purl(),
+		}
+		if sm.Sum != "" {
+			c.Properties = append(c.Properties, &cdxProperty{Name: "go.sum:h1", Value: sm.Sum})
+		}
+		if sm.Replaced != nil {
+			c.Pedigree = &cdxPedigree{Ancestors: []*cdxComponent{{
+				Type:    "library",
+				Name:    sm.Replaced.Path,
+				Version: sm.Replaced.Version,
+				PURL:    goPURL(sm.Replaced.Path, sm.Replaced.Version),
+			}}}
+			c.Properties = append(c.Properties, &cdxProperty{Name: "chainparse:replaces", Value: sm.Replaced.String()})
+		}
+		if sm.LocalPath != "" {
+			c.Properties = append(c.Properties, &cdxProperty{Name: "chainparse:replaced_by_directory", Value: sm.LocalPath})
+		}
+		if sm.Indirect {
+			c.Properties = append(c.Properties, &cdxProperty{Name: "chainparse:indirect", Value: "true"})
+		}
+		bom.Components = append(bom.Components, c)
+		rootDeps.DependsOn = append(rootDeps.DependsOn, c.BOMRef)
+	}
+	bom.Dependencies = []*cdxDependency{rootDeps}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(bom)
+}
+
+type spdxDocument struct {
+	SPDXVersion       string              `json:"spdxVersion"`
+	DataLicense       string              `json:"dataLicense"`
+	SPDXID            string              `json:"SPDXID"`
+	Name              string              `json:"name"`
+	DocumentNamespace string              `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo    `json:"creationInfo"`
+	Packages          []*spdxPackage      `json:"packages"`
+	Relationships     []*spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name             string             `json:"name"`
+	SPDXID           string             `json:"SPDXID"`
+	VersionInfo      string             `json:"versionInfo,omitempty"`
+	DownloadLocation string             `json:"downloadLocation"`
+	FilesAnalyzed    bool               `json:"filesAnalyzed"`
+	LicenseConcluded string             `json:"licenseConcluded"`
+	LicenseDeclared  string             `json:"licenseDeclared"`
+	CopyrightText    string             `json:"copyrightText"`
+	ExternalRefs     []*spdxExternalRef `json:"externalRefs,omitempty"`
+	Comment          string             `json:"comment,omitempty"`
+	Annotations      []*spdxAnnotation  `json:"annotations,omitempty"`
+}
+
+type spdxAnnotation struct {
+	AnnotationDate string `json:"annotationDate"`
+	AnnotationType string `json:"annotationType"`
+	Annotator      string `json:"annotator"`
+	Comment        string `json:"comment"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+var reSPDXIDInvalid = regexp.MustCompile(`[^a-zA-Z0-9.-]+`)
+
+// spdxID returns an SPDX identifier for the i-th package, which only
+// allows letters, digits, dots and dashes.
+func spdxID(i int, name string) string {
+	return fmt.Sprintf("SPDXRef-Package-%d-%s", i, reSPDXIDInvalid.ReplaceAllString(name, "-"))
+}
+
+// spdxDownloadLocation returns the module proxy URL of the module's zip file.
+func spdxDownloadLocation(path, version string) string {
+	escPath, err := module.EscapePath(path)
+	if err != nil || version == "" {
+		return "NOASSERTION"
+	}
+	escVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return "NOASSERTION"
+	}
+	return "https://proxy.golang.org/" + escPath + "/@v/" + escVersion + ".zip"
+}
+
+// WriteSPDX is like WriteCycloneDX but writes an SPDX 2.3 JSON document, in
+// which replace directives are recorded in the comments of the packages and
+// go.sum hashes in their annotations.
+func WriteSPDX(w io.Writer, cs *ChainSchema, generatedAt time.Time) error {
+	gm := cs.GoModule
+	if gm == nil {
+		return errNoGoModule
+	}
+	version := ""
+	if cs.Codebase != nil {
+		version = cs.Codebase.RecommendedVersion
+	}
+	newPackage := func(i int, path, version string) *spdxPackage {
+		return &spdxPackage{
+			Name:             path,
+			SPDXID:           spdxID(i, path),
+			VersionInfo:      version,
+			DownloadLocation: "NOASSERTION",
+			LicenseConcluded: "NOASSERTION",
+			LicenseDeclared:  "NOASSERTION",
+			CopyrightText:    "NOASSERTION",
+			ExternalRefs: []*spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  goPURL(path, version),
+			}},
+		}
+	}
+
+	root := newPackage(0, gm.Path, version)
+	root.Comment = "The binary of the " + cs.ChainName + " chain."
+	if cs.Repo != nil {
+		root.DownloadLocation = "git+" + cs.Repo.URL()
+		if version != "" {
+			root.DownloadLocation += "@" + version
+		}
+	}
+	name := cs.ChainName
+	if version != "" {
+		name += "-" + version
+	}
+	created := generatedAt.UTC().Format(time.RFC3339)
+	doc := &spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              name,
+		DocumentNamespace: "https://github.com/cosmos/chainparse/spdx/" + purlEscape(name) + "-" + sbomUUID(root.ExternalRefs[0].ReferenceLocator+" "+created),
+		CreationInfo: spdxCreationInfo{
+			Created:  created,
+			Creators: []string{"Tool: chainparse"},
+		},
+		Packages: []*spdxPackage{root},
+		Relationships: []*spdxRelationship{{
+			SPDXElementID:      "SPDXRef-DOCUMENT",
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: root.SPDXID,
+		}},
+	}
+
+	for i, sm := range sbomModules(gm) {
+		pkg := newPackage(i+1, sm.Path, sm.Version)
+		if sm.LocalPath == "" {
+			pkg.DownloadLocation = spdxDownloadLocation(sm.Path, sm.Version)
+		}
+		if sm.Sum != "" {
+			pkg.Annotations = []*spdxAnnotation{{
+				AnnotationDate: created,
+				AnnotationType: "OTHER",
+				Annotator:      "Tool: chainparse",
+				Comment:        "go.sum:h1 " + sm.Sum,
+			}}
+		}
+		switch {
+		case sm.Replaced != nil:
+			pkg.Comment = "Replaces " + sm.Replaced.String() + "."
+		case sm.LocalPath != "":
+			pkg.Comment = "Replaced by the directory " + sm.LocalPath + "."
+		}
+		doc.Packages = append(doc.Packages, pkg)
+
+		doc.Relationships = append(doc.Relationships, &spdxRelationship{
+			SPDXElementID:      root.SPDXID,
+			RelationshipType:   "DEPENDS_ON",
+			RelatedSPDXElement: pkg.SPDXID,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
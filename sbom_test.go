@@ -0,0 +1,187 @@
+package chainparse
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/mod/modfile"
+)
+
+// A go.sum hash for github.com/agoric-labs/cosmos-sdk.
+const testdataSum = "h1:ZXbT9SgOS3QbhhDhdm4m2t4EUHKGC0gFTmPlRrSWTOc="
+
+var testdataGoSum = []byte(`github.com/agoric-labs/cosmos-sdk v0.44.2-alpha.agoric.gaiad.1 ` + testdataSum + `
+github.com/agoric-labs/cosmos-sdk v0.44.2-alpha.agoric.gaiad.1/go.mod h1:4vK4x2ASv3FSVmtr6CP6hcZ8mvI6hD2qk4l0uUfRnV0=
+`)
+
+func sbomChain(t *testing.T) *ChainSchema {
+	modF, err := modfile.Parse("go.mod", testdataGoMod, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := *reportResult.Chains[0]
+	cs.Repo = &RepoID{Host: "github.com", Owner: "Agoric", Repo: "ag0"}
+	cs.GoModule = newGoModule(modF)
+	cs.GoModule.Sums = parseGoSum(testdataGoSum)
+	return &cs
+}
+
+func TestRetrieveGoSum(t *testing.T) {
+	cst := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/Agoric/ag0/agoric-3.1/go.sum" {
+			http.NotFound(rw, req)
+			return
+		}
+		rw.Write(testdataGoSum)
+	}))
+	defer cst.Close()
+	destURL, _ := url.Parse(cst.URL)
+	client := &http.Client{Transport: &alwaysToURLRoundTripper{next: cst.Client(), destURL: destURL}}
+
+	fr := newFetcher(nil, WithGoSums())
+	cs := sbomChain(t)
+	cs.GoModule.Sums = nil
+	if warning := fr.retrieveGoSum(context.Background(), client, *cs.Repo, cs); warning != "" {
+		t.Fatal(warning)
+	}
+	want := map[string]string{"github.com/agoric-labs/cosmos-sdk@v0.44.2-alpha.agoric.gaiad.1": testdataSum}
+	if diff := cmp.Diff(cs.GoModule.Sums, want); diff != "" {
+		t.Errorf("Sums mismatch: got - want +\n%s", diff)
+	}
+
+	cs.Codebase = &Codebase{RecommendedVersion: "agoric-3.2"}
+	if warning := fr.retrieveGoSum(context.Background(), client, *cs.Repo, cs); !strings.Contains(warning, "404") {
+		t.Errorf("Expected a warning about the missing go.sum, got %q", warning)
+	}
+	cs.Resolved = &RefResolution{Source: resolvedFromProxy, Module: "github.com/Agoric/ag0@v3.1.0"}
+	if warning := fr.retrieveGoSum(context.Background(), client, *cs.Repo, cs); !strings.Contains(warning, "module proxy") {
+		t.Errorf("Expected a warning about the module proxy, got %q", warning)
+	}
+}
+
+func TestGoPURL(t *testing.T) {
+	tests := []struct{ path, version, want string }{
+		{"github.com/cosmos/cosmos-sdk", "v0.45.4", "pkg:golang/github.com/cosmos/cosmos-sdk@v0.45.4"},
+		{"github.com/Workiva/go-datastructures", "v1.0.53", "pkg:golang/github.com/Workiva/go-datastructures@v1.0.53"},
+		{"github.com/btcsuite/btcd", "v0.0.0+incompatible", "pkg:golang/github.com/btcsuite/btcd@v0.0.0%2Bincompatible"},
+	}
+	for _, tt := range tests {
+		if got := goPURL(tt.path, tt.version); got != tt.want {
+			t.Errorf("goPURL(%q, %q): got %q, want %q", tt.path, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestWriteCycloneDX(t *testing.T) {
+	var b strings.Builder
+	if err := WriteCycloneDX(&b, sbomChain(t), reportTime); err != nil {
+		t.Fatal(err)
+	}
+	var bom struct {
+		BOMFormat    string
+		SpecVersion  string
+		Metadata     struct{ Component cdxComponent }
+		Components   []*cdxComponent
+		Dependencies []*cdxDependency
+	}
+	if err := json.Unmarshal([]byte(b.String()), &bom); err != nil {
+		t.Fatal(err)
+	}
+	if g, w := bom.Metadata.Component.PURL, "pkg:golang/github.com/cosmos/gaia/v6@agoric-3.1"; g != w {
+		t.Errorf("Root purl: got %q, want %q", g, w)
+	}
+
+	refs := make(map[string]bool)
+	var fork *cdxComponent
+	for _, c := range bom.Components {
+		if refs[c.BOMRef] {
+			t.Errorf("Duplicate bom-ref %q", c.BOMRef)
+		}
+		refs[c.BOMRef] = true
+		if c.Name == "github.com/agoric-labs/cosmos-sdk" {
+			fork = c
+		}
+	}
+	if g, w := len(bom.Dependencies[0].DependsOn), len(bom.Components); g != w {
+		t.Errorf("Root dependencies: got %d, want %d", g, w)
+	}
+	want := &cdxComponent{
+		Type:    "library",
+		BOMRef:  "pkg:golang/github.com/agoric-labs/cosmos-sdk@v0.44.2-alpha.agoric.gaiad.1",
+		Name:    "github.com/agoric-labs/cosmos-sdk",
+		Version: "v0.44.2-alpha.agoric.gaiad.1",
+		PURL:    "pkg:golang/github.com/agoric-labs/cosmos-sdk@v0.44.2-alpha.agoric.gaiad.1",
+		Pedigree: &cdxPedigree{Ancestors: []*cdxComponent{{
+			Type:    "library",
+			Name:    "github.com/cosmos/cosmos-sdk",
+			Version: "v0.44.1",
+			PURL:    "pkg:golang/github.com/cosmos/cosmos-sdk@v0.44.1",
+		}}},
+		Properties: []*cdxProperty{
+			{Name: "go.sum:h1", Value: testdataSum},
+			{Name: "chainparse:replaces", Value: "github.com/cosmos/cosmos-sdk@v0.44.1"},
+		},
+	}
+	if diff := cmp.Diff(fork, want); diff != "" {
+		t.Errorf("Forked Cosmos SDK mismatch: got - want +\n%s", diff)
+	}
+}
+
+func TestWriteSPDX(t *testing.T) {
+	var b strings.Builder
+	if err := WriteSPDX(&b, sbomChain(t), reportTime); err != nil {
+		t.Fatal(err)
+	}
+	var doc spdxDocument
+	if err := json.Unmarshal([]byte(b.String()), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if g, w := doc.Packages[0].DownloadLocation, "git+https://github.com/Agoric/ag0@agoric-3.1"; g != w {
+		t.Errorf("Root download location: got %q, want %q", g, w)
+	}
+	if g, w := len(doc.Relationships), len(doc.Packages); g != w {
+		t.Errorf("Relationships: got %d, want %d", g, w)
+	}
+
+	reID := regexp.MustCompile(`^SPDXRef-[a-zA-Z0-9.-]+$`)
+	ids := make(map[string]bool)
+	var fork *spdxPackage
+	for _, pkg := range doc.Packages {
+		if !reID.MatchString(pkg.SPDXID) || ids[pkg.SPDXID] {
+			t.Errorf("Invalid or duplicate SPDXID %q", pkg.SPDXID)
+		}
+		ids[pkg.SPDXID] = true
+		if pkg.Name == "github.com/agoric-labs/cosmos-sdk" {
+			fork = pkg
+		}
+	}
+	if fork == nil {
+		t.Fatal("No package for the forked Cosmos SDK")
+	}
+	if g, w := fork.Comment, "Replaces github.com/cosmos/cosmos-sdk@v0.44.1."; g != w {
+		t.Errorf("Comment: got %q, want %q", g, w)
+	}
+	wantAnnotations := []*spdxAnnotation{{
+		AnnotationDate: "2022-10-01T12:00:00Z",
+		AnnotationType: "OTHER",
+		Annotator:      "Tool: chainparse",
+		Comment:        "go.sum:h1 " + testdataSum,
+	}}
+	if diff := cmp.Diff(fork.Annotations, wantAnnotations); diff != "" {
+		t.Errorf("Annotations mismatch: got - want +\n%s", diff)
+	}
+	if g, w := fork.DownloadLocation, "https://proxy.golang.org/github.com/agoric-labs/cosmos-sdk/@v/v0.44.2-alpha.agoric.gaiad.1.zip"; g != w {
+		t.Errorf("Download location: got %q, want %q", g, w)
+	}
+
+	if err := WriteSPDX(&b, reportResult.Chains[1], reportTime); err == nil {
+		t.Error("Expected an error for a chain without its go.mod retained")
+	}
+}
@@ -0,0 +1,410 @@
+package chainparse
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	sheetsAPIURL = "https://sheets.googleapis.com/v4/spreadsheets"
+	sheetsScope  = "https://www.googleapis.com/auth/spreadsheets"
+
+	defaultGoogleTokenURI = "https://oauth2.googleapis.com/token"
+)
+
+// ServiceAccount holds the fields of a Google service account key file that
+// are needed to authenticate to the Sheets API.
+type ServiceAccount struct {
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// LoadServiceAccount reads the JSON key file of a Google service account.
+func LoadServiceAccount(path string) (*ServiceAccount, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sa := new(ServiceAccount)
+	if err := json.Unmarshal(blob, sa); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if sa.ClientEmail == "" || sa.PrivateKey == "" {
+		return nil, fmt.Errorf("%s: not a service account key file, client_email or private_key is missing", path)
+	}
+	if sa.TokenURI == "" {
+		sa.TokenURI = defaultGoogleTokenURI
+	}
+	return sa, nil
+}
+
+func (sa *ServiceAccount) rsaKey() (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(sa.PrivateKey))
+	if block == nil {
+		return nil, errors.New("service account private_key isn't PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the service account private_key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("service account private_key is a %T, not an RSA key", key)
+	}
+	return rsaKey, nil
+}
+
+// assertion returns the JWT, signed with RS256, that is exchanged for an
+// access token as per https://developers.google.com/identity/protocols/oauth2/service-account.
+func (sa *ServiceAccount) assertion(now time.Time) (string, error) {
+	key, err := sa.rsaKey()
+	if err != nil {
+		return "", err
+	}
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": sa.PrivateKeyID})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   sa.ClientEmail,
+		"scope": sheetsScope,
+		"aud":   sa.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	signed := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + enc.EncodeToString(sig), nil
+}
+
+// SheetsColumns are the columns of the chainparse spreadsheet that
+// SheetsWriter fills in, the others being left to manual edits.
+var SheetsColumns = []Column{
+	{Path: "codebase.git_repo", Header: "Git_Repo"},
+	{Path: "is_mainnet", Header: "Is_mainnet"},
+	{Path: "codebase.recommended_version", Header: "Mainnet GH release"},
+	{Path: "cosmos_sdk_version", Header: "CosmosSDK"},
+	{Path: "tendermint_version", Header: "Tendermint"},
+	{Path: "ibc_version", Header: "IBC"},
+}
+
+// SheetsWriter writes results into a Google spreadsheet with the Sheets API,
+// superseding google_appscript/main.js.
+//
+// Sheets are expected to have their headers in the first row. Rows are
+// matched to chains by the cell under KeyHeader, case insensitively, and only
+// the cells under the headers of Columns are written, so that any other
+// column can be edited by hand. Chains without a row get one appended.
+type SheetsWriter struct {
+	// Columns are the columns to fill in, SheetsColumns by default.
+	Columns []Column
+
+	// KeyHeader is the header of the column holding each row's chain_name,
+	// "chain_name" by default.
+	KeyHeader string
+
+	// ProjectsSheet is the sheet to write the chains as of their
+	// recommended version to, "Projects" by default.
+	ProjectsSheet string
+
+	// LatestSheet is the sheet to write the chains as of the latest
+	// analysis to, "LatestProjects" by default. Chains for which the latest
+	// analysis didn't report anything are written as of their recommended
+	// version. Set it to "-" to skip it.
+	LatestSheet string
+
+	client        *http.Client
+	account       *ServiceAccount
+	spreadsheetID string
+
+	mu          sync.Mutex
+	accessToken string
+	expiry      time.Time
+}
+
+// NewSheetsWriter returns a SheetsWriter for the spreadsheet with the given ID,
+// authenticated as the service account, which must have been granted edit
+// access to the spreadsheet.
+func NewSheetsWriter(rt http.RoundTripper, account *ServiceAccount, spreadsheetID string) *SheetsWriter {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &SheetsWriter{
+		Columns:       SheetsColumns,
+		KeyHeader:     "chain_name",
+		ProjectsSheet: "Projects",
+		LatestSheet:   "LatestProjects",
+
+		client:        &http.Client{Transport: rt},
+		account:       account,
+		spreadsheetID: spreadsheetID,
+	}
+}
+
+// SheetsUpdate summarizes what SheetsWriter.Write changed.
+type SheetsUpdate struct {
+	UpdatedRows  int
+	UpdatedCells int
+	AppendedRows int
+}
+
+func (sw *SheetsWriter) token(ctx context.Context) (string, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	now := time.Now()
+	if sw.accessToken != "" && now.Before(sw.expiry) {
+		return sw.accessToken, nil
+	}
+	assertion, err := sw.account.assertion(now)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", sw.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := sw.do(req, &tok); err != nil {
+		return "", fmt.Errorf("obtaining an access token: %w", err)
+	}
+	sw.accessToken = tok.AccessToken
+	// Renew the token a minute early so that it doesn't expire mid-request.
+	sw.expiry = now.Add(time.Duration(tok.ExpiresIn)*time.Second - time.Minute)
+	return sw.accessToken, nil
+}
+
+// do sends req and decodes the JSON response into v.
+func (sw *SheetsWriter) do(req *http.Request, v interface{}) error {
+	res, err := sw.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	blob, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("%s %s failed with status %q: %s", req.Method, req.URL, res.Status, bytes.TrimSpace(blob))
+	}
+	return json.Unmarshal(blob, v)
+}
+
+func (sw *SheetsWriter) api(ctx context.Context, method, path string, body, v interface{}) error {
+	token, err := sw.token(ctx)
+	if err != nil {
+		return err
+	}
+	var r io.Reader
+	if body != nil {
+		blob, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(blob)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, sheetsAPIURL+"/"+url.PathEscape(sw.spreadsheetID)+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return sw.do(req, v)
+}
+
+// sheetsValueRange is a ValueRange of the Sheets API, whose null values
+// leave the cells they fall on unchanged.
+type sheetsValueRange struct {
+	Range  string          `json:"range"`
+	Values [][]interface{} `json:"values"`
+}
+
+// sheetRange returns the A1 notation of the range starting at the zero based
+// column col of the one based row of sheet.
+func sheetRange(sheet string, col, row int) string {
+	return fmt.Sprintf("'%s'!%s%d", strings.ReplaceAll(sheet, "'", "''"), xlsxColumn(col), row)
+}
+
+// updates returns the value ranges bringing sheet, whose cells are grid,
+// up to date with chains.
+func (sw *SheetsWriter) updates(sheet string, grid [][]string, chains []*ChainSchema, su *SheetsUpdate) ([]*sheetsValueRange, error) {
+	if len(grid) == 0 {
+		return nil, fmt.Errorf("sheet %q has no header row", sheet)
+	}
+	headers := make(map[string]int)
+	for i, header := range grid[0] {
+		headers[strings.TrimSpace(header)] = i
+	}
+	keyCol, ok := headers[sw.KeyHeader]
+	if !ok {
+		return nil, fmt.Errorf("sheet %q has no %q column to match rows by", sheet, sw.KeyHeader)
+	}
+	cols := make(map[int]Column)
+	for _, col := range sw.Columns {
+		i, ok := headers[col.Title()]
+		if !ok {
+			logrus.WithField("sheet", sheet).Warnf("no %q column to write", col.Title())
+			continue
+		}
+		cols[i] = col
+	}
+
+	rowByKey := make(map[string]int)
+	for i, row := range grid[1:] {
+		if keyCol < len(row) {
+			if key := strings.ToLower(strings.TrimSpace(row[keyCol])); key != "" {
+				rowByKey[key] = i + 1
+			}
+		}
+	}
+
+	var vrs []*sheetsValueRange
+	nextRow := len(grid)
+	for _, cs := range chains {
+		i, ok := rowByKey[strings.ToLower(cs.ChainName)]
+		var existing []string
+		if ok {
+			existing = grid[i]
+		} else {
+			i = nextRow
+			nextRow++
+		}
+
+		// A row spanning the written columns, null where the cell is to be left alone.
+		var values []interface{}
+		set := func(col int, value string) {
+			for len(values) <= col {
+				values = append(values, nil)
+			}
+			values[col] = value
+		}
+		changed := 0
+		for col, column := range cols {
+			// The Sheets API leaves out trailing empty cells.
+			current, value := "", column.Value(cs)
+			if col < len(existing) {
+				current = existing[col]
+			}
+			if current == value {
+				continue
+			}
+			set(col, value)
+			changed++
+		}
+		if !ok {
+			set(keyCol, cs.ChainName)
+			su.AppendedRows++
+		} else if changed == 0 {
+			continue
+		} else {
+			su.UpdatedRows++
+		}
+		su.UpdatedCells += changed
+
+		first := 0
+		for values[first] == nil {
+			first++
+		}
+		vrs = append(vrs, &sheetsValueRange{
+			Range:  sheetRange(sheet, first, i+1),
+			Values: [][]interface{}{values[first:]},
+		})
+	}
+	return vrs, nil
+}
+
+// Write brings the spreadsheet up to date with res in a single batch update.
+func (sw *SheetsWriter) Write(ctx context.Context, res *Result) (*SheetsUpdate, error) {
+	sheets := []struct {
+		name   string
+		chains []*ChainSchema
+	}{{sw.ProjectsSheet, res.Chains}}
+	if sw.LatestSheet != "-" {
+		latest := make([]*ChainSchema, 0, len(res.Chains))
+		for _, cs := range res.Chains {
+			if cs.Latest != nil {
+				latest = append(latest, cs.Latest)
+			} else {
+				latest = append(latest, cs)
+			}
+		}
+		sheets = append(sheets, struct {
+			name   string
+			chains []*ChainSchema
+		}{sw.LatestSheet, latest})
+	}
+
+	su := new(SheetsUpdate)
+	var data []*sheetsValueRange
+	for _, sheet := range sheets {
+		var grid struct {
+			Values [][]string `json:"values"`
+		}
+		path := "/values/" + url.PathEscape(fmt.Sprintf("'%s'", strings.ReplaceAll(sheet.name, "'", "''")))
+		if err := sw.api(ctx, "GET", path, nil, &grid); err != nil {
+			return nil, err
+		}
+		vrs, err := sw.updates(sheet.name, grid.Values, sheet.chains, su)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, vrs...)
+	}
+	if len(data) == 0 {
+		return su, nil
+	}
+
+	body := map[string]interface{}{
+		"valueInputOption": "RAW",
+		"data":             data,
+	}
+	if err := sw.api(ctx, "POST", "/values:batchUpdate", body, new(json.RawMessage)); err != nil {
+		return nil, err
+	}
+	return su, nil
+}
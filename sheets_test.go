@@ -0,0 +1,202 @@
+package chainparse
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeSheets is a local stand-in for the token endpoint and the parts of the
+// Sheets API that SheetsWriter uses.
+type fakeSheets struct {
+	t   *testing.T
+	key *rsa.PublicKey
+
+	mu      sync.Mutex
+	grids   map[string][][]string
+	batches int
+}
+
+var reA1Cell = regexp.MustCompile(`^'(.+)'!([A-Z]+)(\d+)$`)
+
+func (fs *fakeSheets) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if req.URL.Path == "/token" {
+		if err := req.ParseForm(); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if g, w := req.Form.Get("grant_type"), "urn:ietf:params:oauth:grant-type:jwt-bearer"; g != w {
+			fs.t.Errorf("grant_type: got %q, want %q", g, w)
+		}
+		parts := strings.Split(req.Form.Get("assertion"), ".")
+		if len(parts) != 3 {
+			http.Error(rw, "malformed assertion", http.StatusBadRequest)
+			return
+		}
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(fs.key, crypto.SHA256, digest[:], sig); err != nil {
+			http.Error(rw, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		var claims map[string]interface{}
+		blob, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		json.Unmarshal(blob, &claims)
+		if g, w := claims["scope"], sheetsScope; g != w {
+			fs.t.Errorf("scope: got %v, want %q", g, w)
+		}
+		rw.Write([]byte(`{"access_token": "ya29.fake", "expires_in": 3600, "token_type": "Bearer"}`))
+		return
+	}
+
+	if g, w := req.Header.Get("Authorization"), "Bearer ya29.fake"; g != w {
+		http.Error(rw, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+	const prefix = "/v4/spreadsheets/sheet-id/values"
+	switch {
+	case req.Method == "GET" && strings.HasPrefix(req.URL.Path, prefix+"/"):
+		sheet := strings.Trim(strings.TrimPrefix(req.URL.Path, prefix+"/"), "'")
+		grid, ok := fs.grids[sheet]
+		if !ok {
+			http.Error(rw, "Unable to parse range: "+sheet, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(rw).Encode(map[string]interface{}{"values": grid})
+
+	case req.Method == "POST" && req.URL.Path == prefix+":batchUpdate":
+		fs.batches++
+		var body struct {
+			ValueInputOption string
+			Data             []struct {
+				Range  string
+				Values [][]*string
+			}
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, vr := range body.Data {
+			m := reA1Cell.FindStringSubmatch(vr.Range)
+			if m == nil {
+				http.Error(rw, "unsupported range "+vr.Range, http.StatusBadRequest)
+				return
+			}
+			col := 0
+			for _, r := range m[2] {
+				col = col*26 + int(r-'A'+1)
+			}
+			row, _ := strconv.Atoi(m[3])
+			grid := fs.grids[m[1]]
+			for len(grid) < row {
+				grid = append(grid, nil)
+			}
+			for i, value := range vr.Values[0] {
+				if value == nil {
+					continue
+				}
+				for len(grid[row-1]) < col+i {
+					grid[row-1] = append(grid[row-1], "")
+				}
+				grid[row-1][col-1+i] = *value
+			}
+			fs.grids[m[1]] = grid
+		}
+		rw.Write([]byte("{}"))
+
+	default:
+		http.NotFound(rw, req)
+	}
+}
+
+func TestSheetsWriter(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account := &ServiceAccount{
+		ClientEmail: "chainparse@project.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI:    "https://oauth2.googleapis.com/token",
+	}
+
+	headers := []string{"chain_name", "Chain", "Git_Repo", "Contact", "Is_mainnet", "Mainnet GH release", "CosmosSDK", "Tendermint", "IBC", "Notes"}
+	fs := &fakeSheets{
+		t:   t,
+		key: &key.PublicKey,
+		grids: map[string][][]string{
+			"Projects": {
+				headers,
+				{"Agoric", "Agoric", "https://github.com/Agoric/ag0", "alice@example.com", "", "agoric-3.0", "v0.44.0", "", "", "upgrading soon"},
+				{"cosmoshub", "Cosmos Hub", "https://github.com/cosmos/gaia", "", "", "v7.0.0", "v0.45.4", "v0.34.19", "v3.0.0@github.com/cosmos/ibc-go/v3"},
+				{"retired", "Retired", "https://github.com/retired/chain"},
+			},
+			"LatestProjects": {headers},
+		},
+	}
+	cst := httptest.NewServer(fs)
+	defer cst.Close()
+	destURL, _ := url.Parse(cst.URL)
+
+	sw := NewSheetsWriter(&alwaysToURLRoundTripper{next: cst.Client(), destURL: destURL}, account, "sheet-id")
+	su, err := sw.Write(context.Background(), reportResult)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(su, &SheetsUpdate{UpdatedRows: 1, UpdatedCells: 4 + 1 + 11, AppendedRows: 1 + 3}); diff != "" {
+		t.Errorf("SheetsUpdate mismatch: got - want +\n%s", diff)
+	}
+	if g, w := fs.batches, 1; g != w {
+		t.Errorf("Batch updates: got %d, want %d", g, w)
+	}
+
+	wantProjects := [][]string{
+		headers,
+		// The manually edited Chain, Contact and Notes columns are left alone.
+		{"Agoric", "Agoric", "https://github.com/Agoric/ag0", "alice@example.com", "", "agoric-3.1", "v0.44.2-alpha.agoric.gaiad.1@github.com/agoric-labs/cosmos-sdk", "v0.34.13@github.com/tendermint/tendermint", "v1.2.0", "upgrading soon"},
+		{"cosmoshub", "Cosmos Hub", "https://github.com/cosmos/gaia", "", "", "v7.0.0", "v0.45.4", "v0.34.19", "v3.0.0@github.com/cosmos/ibc-go/v3"},
+		{"retired", "Retired", "https://github.com/retired/chain"},
+		{"theta", "", "", "", "", "", "v0.45.4"},
+	}
+	if diff := cmp.Diff(fs.grids["Projects"], wantProjects); diff != "" {
+		t.Errorf("Projects mismatch: got - want +\n%s", diff)
+	}
+	if g, w := len(fs.grids["LatestProjects"]), 4; g != w {
+		t.Errorf("LatestProjects rows: got %d, want %d", g, w)
+	}
+
+	// Writing the same results again is a no-op.
+	if su, err = sw.Write(context.Background(), reportResult); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(su, new(SheetsUpdate)); diff != "" {
+		t.Errorf("SheetsUpdate mismatch: got - want +\n%s", diff)
+	}
+	if g, w := fs.batches, 1; g != w {
+		t.Errorf("Batch updates: got %d, want %d", g, w)
+	}
+}
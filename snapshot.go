@@ -0,0 +1,149 @@
+package chainparse
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultRefreshInterval is how often the server refreshes its snapshot
+// unless told otherwise.
+const DefaultRefreshInterval = 15 * time.Minute
+
+// snapshot is the Result of a successful refresh, served until the next one.
+type snapshot struct {
+	res *Result
+	at  time.Time
+
+	// responses caches the representations of the snapshot rendered so far,
+	// keyed by what they represent, see snapshotResponse.
+	mu        sync.Mutex
+	responses map[string]*snapshotResponse
+
+	reportOnce sync.Once
+	rep        *Report
+}
+
+// snapshotCall is a refresh under way, which concurrent callers wait for
+// instead of crawling the registry all over again.
+type snapshotCall struct {
+	done chan struct{}
+	snap *snapshot
+	err  error
+}
+
+// snapshot returns the last good snapshot, refreshing only if there isn't any yet.
+func (fr *fetcher) snapshot(ctx context.Context) (*snapshot, error) {
+	fr.mu.Lock()
+	snap := fr.snap
+	fr.mu.Unlock()
+	if snap != nil {
+		return snap, nil
+	}
+	return fr.refreshSnapshot(ctx)
+}
+
+// detachedContext carries the values of its parent, such as its trace span,
+// but not its cancellation, like context.WithoutCancel of Go 1.21.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// refreshSnapshot takes a new snapshot, or waits for the one being taken.
+// The previous snapshot is kept if the refresh fails. The refresh is shared
+// by every caller, so it isn't cancelled along with ctx, such as when the
+// client whose request started it disconnects, but callers stop waiting for
+// it once their ctx is done.
+func (fr *fetcher) refreshSnapshot(ctx context.Context) (*snapshot, error) {
+	fr.mu.Lock()
+	call := fr.snapCall
+	if call == nil {
+		call = &snapshotCall{done: make(chan struct{})}
+		fr.snapCall = call
+		go fr.takeSnapshot(detachedContext{ctx}, call)
+	}
+	fr.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.snap, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// takeSnapshot crawls the registry for call, storing the snapshot if it succeeds.
+func (fr *fetcher) takeSnapshot(ctx context.Context, call *snapshotCall) {
+	res, err := fr.fetchResult(ctx)
+	if err == nil {
+		call.snap = &snapshot{res: res, at: time.Now()}
+	}
+	call.err = err
+
+	fr.mu.Lock()
+	fr.snapCall = nil
+	if call.snap != nil {
+		fr.snap = call.snap
+	}
+	fr.mu.Unlock()
+	close(call.done)
+}
+
+// report returns the Report of the snapshot, built on first use.
+func (snap *snapshot) report() *Report {
+	snap.reportOnce.Do(func() {
+		snap.rep = NewReport(snap.res, snap.at)
+	})
+	return snap.rep
+}
+
+// setHeaders sets the Last-Modified and Age headers of a response served from the snapshot.
+func (snap *snapshot) setHeaders(rw http.ResponseWriter, now time.Time) {
+	age := now.Sub(snap.at)
+	if age < 0 {
+		age = 0
+	}
+	rw.Header().Set("Last-Modified", snap.at.UTC().Format(http.TimeFormat))
+	rw.Header().Set("Age", strconv.Itoa(int(age/time.Second)))
+}
+
+// Refresh takes a snapshot of the registry right away and then every
+// interval until ctx is done, so that the handlers serve the last snapshot
+// instantly instead of crawling the registry on every request. A failed
+// refresh is logged and the previous snapshot is served until the next one
+// succeeds. onRefresh, if not nil, is called with the outcome of every
+// refresh, such as to save what was recorded during it. Without Refresh
+// running, the handlers take a snapshot on the first request and serve it
+// from then on.
+func (cp *ChainParser) Refresh(ctx context.Context, interval time.Duration, onRefresh func(error)) {
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		start := time.Now()
+		_, err := cp.fetcher.refreshSnapshot(ctx)
+		if err != nil {
+			logrus.WithContext(ctx).WithError(err).Error("failed to refresh the snapshot, serving the previous one")
+		} else {
+			logrus.WithContext(ctx).WithField("took", time.Since(start)).Info("refreshed the snapshot")
+		}
+		if onRefresh != nil {
+			onRefresh(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package chainparse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	var downloads int32
+	var failing, goMod atomic.Value
+	failing.Store(false)
+	goMod.Store(testdataGoMod)
+	cst := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "go.mod"):
+			rw.Write(goMod.Load().([]byte))
+		case strings.HasSuffix(req.URL.Path, "master.zip"):
+			atomic.AddInt32(&downloads, 1)
+			if failing.Load().(bool) {
+				http.Error(rw, "registry unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			rw.Write(testdataZip)
+		default:
+			http.NotFound(rw, req)
+		}
+	}))
+	defer cst.Close()
+	destURL, _ := url.Parse(cst.URL)
+	cp := NewChainParser(&alwaysToURLRoundTripper{next: cst.Client(), destURL: destURL})
+
+	// Concurrent requests without a snapshot share a single refresh.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			cp.FetchData(rec, httptest.NewRequest("GET", "/", nil))
+			if rec.Code != http.StatusOK {
+				t.Errorf("Status: got %d, want %d", rec.Code, http.StatusOK)
+			}
+		}()
+	}
+	wg.Wait()
+	if g, w := atomic.LoadInt32(&downloads), int32(1); g != w {
+		t.Fatalf("Registry downloads: got %d, want %d", g, w)
+	}
+
+	cp.fetcher.mu.Lock()
+	taken := cp.fetcher.snap
+	taken.at = taken.at.Add(-90 * time.Second)
+	cp.fetcher.mu.Unlock()
+
+	rec := httptest.NewRecorder()
+	cp.FetchData(rec, httptest.NewRequest("GET", "/", nil))
+	if g, w := rec.Header().Get("Age"), "90"; g != w {
+		t.Errorf("Age: got %q, want %q", g, w)
+	}
+	if g, w := rec.Header().Get("Last-Modified"), taken.at.UTC().Format(http.TimeFormat); g != w {
+		t.Errorf("Last-Modified: got %q, want %q", g, w)
+	}
+	if g, w := atomic.LoadInt32(&downloads), int32(1); g != w {
+		t.Errorf("Serving the snapshot shouldn't download the registry, got %d downloads", g)
+	}
+
+	// A failed refresh keeps the previous snapshot.
+	failing.Store(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	refreshed := make(chan error, 1)
+	go func() {
+		cp.Refresh(ctx, time.Hour, func(err error) { refreshed <- err })
+		close(done)
+	}()
+	if err := <-refreshed; err == nil {
+		t.Error("Expected the refresh to fail")
+	}
+	cancel()
+	<-done
+
+	rec = httptest.NewRecorder()
+	cp.FetchData(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status after a failed refresh: got %d, want %d", rec.Code, http.StatusOK)
+	}
+	var byPrettyName map[string]*ChainSchema
+	if err := json.Unmarshal(rec.Body.Bytes(), &byPrettyName); err != nil {
+		t.Fatal(err)
+	}
+	if len(byPrettyName) != len(taken.res.Chains) {
+		t.Errorf("Chains after a failed refresh: got %d, want %d", len(byPrettyName), len(taken.res.Chains))
+	}
+	if cp.fetcher.snap != taken {
+		t.Error("A failed refresh replaced the snapshot")
+	}
+
+	// The next refresh sees the go.mod files as they are now, not as cached by the first one.
+	failing.Store(false)
+	goMod.Store(bytes.ReplaceAll(testdataGoMod, []byte("v0.44.2-alpha.agoric.gaiad.1"), []byte("v0.44.3-alpha.agoric.gaiad.2")))
+	snap, err := cp.fetcher.refreshSnapshot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cs := range snap.res.Chains {
+		if g, w := cs.CosmosSDKVersion, "v0.44.3-alpha.agoric.gaiad.2@github.com/agoric-labs/cosmos-sdk"; g != w {
+			t.Errorf("%s: Cosmos SDK after the go.mod changed: got %q, want %q", cs.ChainName, g, w)
+		}
+	}
+}
+
+func TestSnapshotOutlivesCaller(t *testing.T) {
+	release := make(chan struct{})
+	cst := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "go.mod"):
+			rw.Write(testdataGoMod)
+		case strings.HasSuffix(req.URL.Path, "master.zip"):
+			<-release
+			rw.Write(testdataZip)
+		default:
+			http.NotFound(rw, req)
+		}
+	}))
+	defer cst.Close()
+	destURL, _ := url.Parse(cst.URL)
+	cp := NewChainParser(&alwaysToURLRoundTripper{next: cst.Client(), destURL: destURL})
+
+	// The client whose request started the refresh goes away mid-crawl.
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := cp.fetcher.snapshot(ctx)
+		errc <- err
+	}()
+	for {
+		cp.fetcher.mu.Lock()
+		started := cp.fetcher.snapCall != nil
+		cp.fetcher.mu.Unlock()
+		if started {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("The cancelled caller got %v, want %v", err, context.Canceled)
+	}
+
+	// The refresh carries on for everyone else.
+	close(release)
+	snap, err := cp.fetcher.snapshot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.res.Chains) == 0 {
+		t.Error("The snapshot has no chains")
+	}
+}
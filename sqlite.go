@@ -0,0 +1,247 @@
+package chainparse
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// sqliteSchema normalizes a Result into tables keyed by the run that produced
+// them, so that runs can be appended to the same database and compared.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	started_at TEXT NOT NULL,
+	summary    TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS chains (
+	run_id         INTEGER NOT NULL REFERENCES runs (id),
+	chain_name     TEXT NOT NULL,
+	pretty_name    TEXT,
+	network_type   TEXT,
+	status         TEXT,
+	bech32_prefix  TEXT,
+	is_mainnet     TEXT,
+	archived       INTEGER NOT NULL DEFAULT 0,
+	latest_release TEXT,
+	PRIMARY KEY (run_id, chain_name)
+);
+CREATE INDEX IF NOT EXISTS chains_chain_name ON chains (chain_name);
+
+CREATE TABLE IF NOT EXISTS codebases (
+	run_id              INTEGER NOT NULL REFERENCES runs (id),
+	chain_name          TEXT NOT NULL,
+	git_repo            TEXT,
+	repo_host           TEXT,
+	repo_owner          TEXT,
+	repo_name           TEXT,
+	recommended_version TEXT,
+	PRIMARY KEY (run_id, chain_name)
+);
+CREATE INDEX IF NOT EXISTS codebases_repo ON codebases (repo_host, repo_owner, repo_name);
+
+-- versions lists the recommended and compatible versions of every chain.
+CREATE TABLE IF NOT EXISTS versions (
+	run_id       INTEGER NOT NULL REFERENCES runs (id),
+	chain_name   TEXT NOT NULL,
+	version      TEXT NOT NULL,
+	recommended  INTEGER NOT NULL DEFAULT 0,
+	resolved_ref TEXT,
+	error        TEXT,
+	PRIMARY KEY (run_id, chain_name, version)
+);
+
+-- module_versions lists the tracked modules of every analysed version, the
+-- latest analysis having the analysis column set to "latest".
+CREATE TABLE IF NOT EXISTS module_versions (
+	run_id         INTEGER NOT NULL REFERENCES runs (id),
+	chain_name     TEXT NOT NULL,
+	analysis       TEXT NOT NULL,
+	version        TEXT NOT NULL,
+	module         TEXT NOT NULL,
+	module_version TEXT NOT NULL,
+	replaced_by    TEXT
+);
+CREATE INDEX IF NOT EXISTS module_versions_chain ON module_versions (run_id, chain_name);
+CREATE INDEX IF NOT EXISTS module_versions_module ON module_versions (module, module_version);
+
+CREATE TABLE IF NOT EXISTS replace_directives (
+	run_id      INTEGER NOT NULL REFERENCES runs (id),
+	chain_name  TEXT NOT NULL,
+	old_path    TEXT NOT NULL,
+	old_version TEXT,
+	new_path    TEXT NOT NULL,
+	new_version TEXT
+);
+CREATE INDEX IF NOT EXISTS replace_directives_chain ON replace_directives (run_id, chain_name);
+CREATE INDEX IF NOT EXISTS replace_directives_new_path ON replace_directives (new_path);
+
+CREATE TABLE IF NOT EXISTS outcomes (
+	run_id      INTEGER NOT NULL REFERENCES runs (id),
+	chain_name  TEXT NOT NULL,
+	path        TEXT,
+	status      TEXT NOT NULL,
+	category    TEXT,
+	http_status INTEGER,
+	url         TEXT,
+	message     TEXT,
+	warnings    TEXT
+);
+CREATE INDEX IF NOT EXISTS outcomes_run_status ON outcomes (run_id, status);
+`
+
+// The tracked modules as named in the module_versions table.
+const (
+	moduleCosmosSDK  = "cosmos-sdk"
+	moduleTendermint = "tendermint"
+	moduleIBC        = "ibc-go"
+)
+
+// WriteSQLite appends res to the SQLite database db as a new run, creating the
+// tables if necessary, and returns the id of the run. The replace_directives
+// table is only populated for chains analysed with WithGoModules.
+//
+// The caller picks the database driver, this only relies on database/sql.
+// Chains are keyed by chain_name, so res can't have several chains of the
+// same name.
+func WriteSQLite(ctx context.Context, db *sql.DB, res *Result, startedAt time.Time) (runID int64, rerr error) {
+	if err := checkDuplicateChainNames(res); err != nil {
+		return 0, err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if rerr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, sqliteSchema); err != nil {
+		return 0, err
+	}
+	runRes, err := tx.ExecContext(ctx, `INSERT INTO runs (started_at, summary) VALUES (?, ?)`,
+		startedAt.UTC().Format(time.RFC3339), res.Summary())
+	if err != nil {
+		return 0, err
+	}
+	if runID, err = runRes.LastInsertId(); err != nil {
+		return 0, err
+	}
+
+	exec := func(query string, args ...interface{}) {
+		if err == nil {
+			_, err = tx.ExecContext(ctx, query, append([]interface{}{runID}, args...)...)
+		}
+	}
+	modules := func(chainName, analysis, version string, cs *ChainSchema) {
+		for module, dep := range map[string]string{
+			moduleCosmosSDK:  cs.CosmosSDKVersion,
+			moduleTendermint: cs.TendermintVersion,
+			moduleIBC:        cs.IBCVersion,
+		} {
+			if dep == "" {
+				continue
+			}
+			vers, replacedBy := splitDependency(dep)
+			exec(`INSERT INTO module_versions (run_id, chain_name, analysis, version, module, module_version, replaced_by) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+				chainName, analysis, version, module, vers, nullString(replacedBy))
+		}
+	}
+
+	for _, cs := range res.Chains {
+		exec(`INSERT INTO chains (run_id, chain_name, pretty_name, network_type, status, bech32_prefix, is_mainnet, archived, latest_release) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			cs.ChainName, cs.PrettyName, cs.NetworkType, cs.Status, cs.Bech32Prefix, cs.IsMainnet, cs.Archived, nullString(cs.LatestRelease))
+
+		cb := cs.Codebase
+		if cb == nil {
+			continue
+		}
+		var repo RepoID
+		if cs.Repo != nil {
+			repo = *cs.Repo
+		}
+		exec(`INSERT INTO codebases (run_id, chain_name, git_repo, repo_host, repo_owner, repo_name, recommended_version) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			cs.ChainName, cb.GitRepoURL, nullString(repo.Host), nullString(repo.Owner), nullString(repo.Repo), cb.RecommendedVersion)
+
+		if len(cs.Versions) == 0 {
+			var resolvedRef string
+			if cs.Resolved != nil {
+				resolvedRef = cs.Resolved.Ref
+			}
+			exec(`INSERT INTO versions (run_id, chain_name, version, recommended, resolved_ref) VALUES (?, ?, ?, 1, ?)`,
+				cs.ChainName, cb.RecommendedVersion, nullString(resolvedRef))
+			modules(cs.ChainName, "version", cb.RecommendedVersion, cs)
+		}
+		for _, vi := range cs.Versions {
+			var resolvedRef string
+			if vi.Resolved != nil {
+				resolvedRef = vi.Resolved.Ref
+			}
+			exec(`INSERT INTO versions (run_id, chain_name, version, recommended, resolved_ref, error) VALUES (?, ?, ?, ?, ?, ?)`,
+				cs.ChainName, vi.Version, vi.Recommended, nullString(resolvedRef), nullString(vi.Error))
+			modules(cs.ChainName, "version", vi.Version, &ChainSchema{
+				CosmosSDKVersion:  vi.CosmosSDKVersion,
+				TendermintVersion: vi.TendermintVersion,
+				IBCVersion:        vi.IBCVersion,
+			})
+		}
+		if cs.Latest != nil {
+			modules(cs.ChainName, "latest", "", cs.Latest)
+		}
+
+		if cs.GoModule != nil {
+			for _, rep := range cs.GoModule.Replaces {
+				exec(`INSERT INTO replace_directives (run_id, chain_name, old_path, old_version, new_path, new_version) VALUES (?, ?, ?, ?, ?, ?)`,
+					cs.ChainName, rep.OldPath, nullString(rep.OldVersion), rep.NewPath, nullString(rep.NewVersion))
+			}
+		}
+	}
+
+	for _, out := range res.Outcomes {
+		var httpStatus interface{}
+		if out.HTTPStatus != 0 {
+			httpStatus = out.HTTPStatus
+		}
+		exec(`INSERT INTO outcomes (run_id, chain_name, path, status, category, http_status, url, message, warnings) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			out.ChainName, nullString(out.Path), string(out.Status), nullString(string(out.Category)), httpStatus,
+			nullString(out.URL), nullString(out.Message), nullString(strings.Join(out.Warnings, "\n")))
+	}
+	if err != nil {
+		return 0, err
+	}
+	return runID, tx.Commit()
+}
+
+// checkDuplicateChainNames returns an error naming the chain.json files of
+// the first chain_name that several chains of res share, such as a testnet
+// that copied its mainnet's chain.json, if any.
+func checkDuplicateChainNames(res *Result) error {
+	paths := make(map[string][]string)
+	for _, out := range res.Outcomes {
+		if out.Status == OutcomeOK {
+			paths[out.ChainName] = append(paths[out.ChainName], out.Path)
+		}
+	}
+	seen := make(map[string]bool, len(res.Chains))
+	for _, cs := range res.Chains {
+		if !seen[cs.ChainName] {
+			seen[cs.ChainName] = true
+			continue
+		}
+		if p := paths[cs.ChainName]; len(p) >= 2 {
+			return fmt.Errorf("chain_name %q is used by both %s and %s", cs.ChainName, p[0], p[1])
+		}
+		return fmt.Errorf("chain_name %q is used by several chains", cs.ChainName)
+	}
+	return nil
+}
+
+// nullString stores empty strings as NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
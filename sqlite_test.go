@@ -0,0 +1,126 @@
+package chainparse
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/mod/modfile"
+	_ "modernc.org/sqlite"
+)
+
+func TestWriteSQLite(t *testing.T) {
+	modF, err := modfile.Parse("go.mod", testdataGoMod, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gm := newGoModule(modF)
+	if g, w := gm.Path, "github.com/cosmos/gaia/v6"; g != w {
+		t.Errorf("Path: got %q, want %q", g, w)
+	}
+	if g, w := len(gm.Replaces), 4; g != w {
+		t.Fatalf("Replaces: got %d, want %d", g, w)
+	}
+
+	agoric := *reportResult.Chains[0]
+	agoric.Repo = &RepoID{Host: "github.com", Owner: "Agoric", Repo: "ag0"}
+	agoric.GoModule = gm
+	res := &Result{
+		Chains:   append([]*ChainSchema{&agoric}, reportResult.Chains[1:]...),
+		Outcomes: reportResult.Outcomes,
+	}
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "chainparse.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	for i, want := range []int64{1, 2} {
+		runID, err := WriteSQLite(ctx, db, res, reportTime.Add(time.Duration(i)*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if runID != want {
+			t.Errorf("Run id: got %d, want %d", runID, want)
+		}
+	}
+
+	// Which chains of the latest run run on a forked Cosmos SDK?
+	rows, err := db.QueryContext(ctx, `
+		SELECT c.chain_name, mv.module_version, mv.replaced_by, cb.repo_owner
+		FROM chains c
+		JOIN codebases cb USING (run_id, chain_name)
+		JOIN module_versions mv USING (run_id, chain_name)
+		WHERE c.run_id = (SELECT MAX(id) FROM runs)
+			AND mv.module = 'cosmos-sdk' AND mv.replaced_by IS NOT NULL`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][4]string
+	for rows.Next() {
+		var row [4]string
+		if err := rows.Scan(&row[0], &row[1], &row[2], &row[3]); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, row)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := [][4]string{{"agoric", "v0.44.2-alpha.agoric.gaiad.1", "github.com/agoric-labs/cosmos-sdk", "Agoric"}}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Forked chains mismatch: got - want +\n%s", diff)
+	}
+
+	for _, tt := range []struct {
+		query string
+		want  int
+	}{
+		{`SELECT COUNT(*) FROM chains`, 6},
+		{`SELECT COUNT(*) FROM codebases WHERE run_id = 2`, 2},
+		{`SELECT COUNT(*) FROM versions WHERE run_id = 2 AND recommended`, 2},
+		{`SELECT COUNT(*) FROM replace_directives WHERE run_id = 1 AND new_path = 'github.com/regen-network/protobuf'`, 1},
+		{`SELECT COUNT(*) FROM outcomes WHERE run_id = 1 AND status = 'failed' AND http_status = 404`, 1},
+	} {
+		var got int
+		if err := db.QueryRowContext(ctx, tt.query).Scan(&got); err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestWriteSQLiteDuplicateChainNames(t *testing.T) {
+	copied := *reportResult.Chains[1]
+	copied.NetworkType = "testnet"
+	res := &Result{
+		Chains: append(append([]*ChainSchema(nil), reportResult.Chains...), &copied),
+		Outcomes: []*Outcome{
+			{ChainName: copied.ChainName, Path: "cosmoshub/chain.json", Status: OutcomeOK},
+			{ChainName: copied.ChainName, Path: "testnets/cosmoshubtestnet/chain.json", Status: OutcomeOK},
+		},
+	}
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "chainparse.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = WriteSQLite(context.Background(), db, res, reportTime)
+	if err == nil {
+		t.Fatal("Expected an error for the duplicate chain_name")
+	}
+	for _, path := range []string{"cosmoshub/chain.json", "testnets/cosmoshubtestnet/chain.json"} {
+		if !strings.Contains(err.Error(), path) {
+			t.Errorf("The error doesn't name %s: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package chainparse
+
+import (
+	"encoding/json"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"golang.org/x/mod/semver"
+)
+
+// TemplateData is what user-supplied templates are executed with.
+//
+// Chains and outcomes are referred to by their Go field names, such as
+// {{.ChainName}}, {{.Codebase.RecommendedVersion}} or {{.CosmosSDKVersion}},
+// dependency versions being suffixed with "@" and the module path they were
+// replaced with, if any, as in the JSON output.
+type TemplateData struct {
+	GeneratedAt time.Time
+	Summary     string
+
+	Chains   []*ChainSchema
+	Outcomes []*Outcome
+
+	// Failures holds the outcomes of the chains that failed.
+	Failures []*Outcome
+
+	// Report splits the chains into mainnets and testnets with their
+	// dependencies flagged as outdated or forked, see NewReport.
+	Report *Report
+}
+
+// NewTemplateData builds the TemplateData for res.
+func NewTemplateData(res *Result, generatedAt time.Time) *TemplateData {
+	return &TemplateData{
+		GeneratedAt: generatedAt.UTC(),
+		Summary:     res.Summary(),
+		Chains:      res.Chains,
+		Outcomes:    res.Outcomes,
+		Failures:    res.Failures(),
+		Report:      NewReport(res, generatedAt),
+	}
+}
+
+// templateSemver returns the canonical semantic version of a version or
+// dependency version, or "" if it isn't one.
+func templateSemver(vers string) string {
+	vers, _ = splitDependency(vers)
+	return tagSemver(vers)
+}
+
+// TemplateFuncs are the helper functions available to user-supplied templates
+// on top of the builtin ones of text/template:
+//
+//   - semverCompare a b: -1, 0 or +1 as a is older than, the same as or newer
+//     than b, invalid versions being older than any valid one
+//   - major v and majorMinor v: such as "v0" and "v0.45" for v0.45.4, or ""
+//   - version dep: the version of a dependency without the module it was replaced with
+//   - replacedBy dep: the module path that a dependency was replaced with, or ""
+//   - forked dep: whether the dependency was replaced with a module other than upstream's
+//   - formatTime layout t: t formatted with a time.Format layout
+//   - join sep list: the elements of a list of strings joined by sep
+//   - json v: v encoded as JSON, which is also valid YAML
+//   - markdownCell s: s escaped for use within a Markdown table cell
+var TemplateFuncs = map[string]interface{}{
+	"semverCompare": func(a, b string) int {
+		return semver.Compare(templateSemver(a), templateSemver(b))
+	},
+	"major": func(vers string) string {
+		return semver.Major(templateSemver(vers))
+	},
+	"majorMinor": func(vers string) string {
+		return semver.MajorMinor(templateSemver(vers))
+	},
+	"version": func(dep string) string {
+		vers, _ := splitDependency(dep)
+		return vers
+	},
+	"replacedBy": func(dep string) string {
+		_, replacement := splitDependency(dep)
+		return replacement
+	},
+	"forked": isForkedDependency,
+	"formatTime": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+	"join": func(sep string, elems []string) string {
+		return strings.Join(elems, sep)
+	},
+	"json": func(v interface{}) (string, error) {
+		blob, err := json.Marshal(v)
+		return string(blob), err
+	},
+	"markdownCell": markdownCell,
+}
+
+// Template is a user-supplied template that results can be rendered through.
+type Template struct {
+	name string
+	html bool
+	tmpl interface {
+		Execute(io.Writer, interface{}) error
+	}
+}
+
+// LoadTemplate parses the template file at path, with html/template if its
+// extension is .html or .htm, once any .tmpl suffix is stripped, and with
+// text/template otherwise. The template is named after the file, without
+// its extensions, so slack.txt.tmpl is named slack.
+func LoadTemplate(path string) (*Template, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTemplate(filepath.Base(path), string(blob))
+}
+
+// ParseTemplate is like LoadTemplate for the contents of the file named filename.
+func ParseTemplate(filename, text string) (*Template, error) {
+	name := strings.TrimSuffix(filename, ".tmpl")
+	ext := strings.ToLower(filepath.Ext(name))
+	t := &Template{
+		name: strings.TrimSuffix(name, filepath.Ext(name)),
+		html: ext == ".html" || ext == ".htm",
+	}
+
+	var err error
+	if t.html {
+		t.tmpl, err = htmltemplate.New(filename).Funcs(TemplateFuncs).Parse(text)
+	} else {
+		t.tmpl, err = texttemplate.New(filename).Funcs(TemplateFuncs).Parse(text)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// WithTemplates makes the templates available to the server's /report
+// endpoint, as /report?template=name.
+func WithTemplates(templates ...*Template) Option {
+	return func(fr *fetcher) {
+		if fr.templates == nil {
+			fr.templates = make(map[string]*Template)
+		}
+		for _, t := range templates {
+			fr.templates[t.name] = t
+		}
+	}
+}
+
+// Name returns the name of the template.
+func (t *Template) Name() string { return t.name }
+
+// IsHTML reports whether the template is an html/template one.
+func (t *Template) IsHTML() bool { return t.html }
+
+// Execute renders res through the template.
+func (t *Template) Execute(w io.Writer, res *Result, generatedAt time.Time) error {
+	return t.tmpl.Execute(w, NewTemplateData(res, generatedAt))
+}
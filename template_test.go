@@ -0,0 +1,89 @@
+package chainparse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTemplate(t *testing.T) {
+	tests := []struct {
+		path     string
+		wantName string
+		wantHTML bool
+		want     string
+	}{
+		{
+			path:     "testdata/templates/slack.txt.tmpl",
+			wantName: "slack",
+			want: `*Cosmos chain status* as of Oct 1, 2022 (3 ok, 0 skipped, 1 failed)
+
+• Agoric runs a fork of the Cosmos SDK v0.44: github.com/agoric-labs/cosmos-sdk
+
+:warning: aioz failed: HTTP request failed with status: "404 Not Found"
+`,
+		},
+		{
+			path:     "testdata/templates/chains.html.tmpl",
+			wantName: "chains",
+			wantHTML: true,
+			want: `<ul>
+  <li>Agoric: v0.44.2-alpha.agoric.gaiad.1 (outdated)</li>
+  <li>Cosmos | Hub: v0.45.4</li>
+</ul>
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.wantName, func(t *testing.T) {
+			tmpl, err := LoadTemplate(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g, w := tmpl.Name(), tt.wantName; g != w {
+				t.Errorf("Name: got %q, want %q", g, w)
+			}
+			if g, w := tmpl.IsHTML(), tt.wantHTML; g != w {
+				t.Errorf("IsHTML: got %t, want %t", g, w)
+			}
+			var b strings.Builder
+			if err := tmpl.Execute(&b, reportResult, reportTime); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(b.String(), tt.want); diff != "" {
+				t.Errorf("Output mismatch: got - want +\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	tmpl, err := ParseTemplate("funcs.yaml.tmpl", `{{semverCompare "0.45.4" "v0.45.10"}} {{major "v0.47.0@github.com/org/cosmos-sdk"}} {{json .Summary}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, reportResult, reportTime); err != nil {
+		t.Fatal(err)
+	}
+	if g, w := b.String(), `-1 v0 "3 ok, 0 skipped, 1 failed"`; g != w {
+		t.Errorf("Output: got %q, want %q", g, w)
+	}
+}
+
+func TestFetchReportUnknownTemplate(t *testing.T) {
+	tmpl, err := LoadTemplate("testdata/templates/slack.txt.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := NewChainParser(nil, WithTemplates(tmpl))
+	rec := httptest.NewRecorder()
+	cp.FetchReport(rec, httptest.NewRequest("GET", "/report?template=discord", nil))
+	if g, w := rec.Code, http.StatusNotFound; g != w {
+		t.Errorf("Status: got %d, want %d", g, w)
+	}
+}
@@ -0,0 +1,95 @@
+package chainparse
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// VersionInfo is the analysis of the go.mod file at one of a chain's versions.
+type VersionInfo struct {
+	Version     string `json:"version"`
+	Recommended bool   `json:"recommended,omitempty"`
+
+	// Resolved records the ref that Version resolved to, if it couldn't be used literally.
+	Resolved *RefResolution `json:"resolved,omitempty"`
+
+	CosmosSDKVersion  string `json:"cosmos_sdk_version,omitempty"`
+	TendermintVersion string `json:"tendermint_version,omitempty"`
+	IBCVersion        string `json:"ibc_version,omitempty"`
+
+	// Error explains why the version couldn't be analysed.
+	Error string `json:"error,omitempty"`
+}
+
+// WithCompatibleVersions enables the analysis of every one of the registry's
+// compatible_versions and not just recommended_version, populating
+// ChainSchema.Versions with the resulting per chain version matrix.
+func WithCompatibleVersions() Option {
+	return func(fr *fetcher) {
+		fr.compatibleVersions = true
+	}
+}
+
+// maxVersionsInFlight bounds the concurrent go.mod lookups for a single chain.
+const maxVersionsInFlight = 4
+
+// chainVersions returns the versions to analyse for cb, the recommended
+// version followed by the compatible versions in the registry's order.
+func chainVersions(cb *Codebase) []string {
+	seen := make(map[string]bool)
+	var versions []string
+	for _, vers := range append([]string{cb.RecommendedVersion}, cb.CompatibleVersions...) {
+		if vers == "" || seen[vers] {
+			continue
+		}
+		seen[vers] = true
+		versions = append(versions, vers)
+	}
+	return versions
+}
+
+// retrieveVersions analyses the go.mod file of every one of cs's versions,
+// the recommended version's analysis being cs itself so it's reused as is.
+func (fr *fetcher) retrieveVersions(ctx context.Context, client *http.Client, repo RepoID, cs *ChainSchema) []*VersionInfo {
+	versions := chainVersions(cs.Codebase)
+	infos := make([]*VersionInfo, len(versions))
+
+	sem := make(chan struct{}, maxVersionsInFlight)
+	var wg sync.WaitGroup
+	for i, vers := range versions {
+		if vers == cs.Codebase.RecommendedVersion {
+			infos[i] = &VersionInfo{
+				Version:           vers,
+				Recommended:       true,
+				Resolved:          cs.Resolved,
+				CosmosSDKVersion:  cs.CosmosSDKVersion,
+				TendermintVersion: cs.TendermintVersion,
+				IBCVersion:        cs.IBCVersion,
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, vers string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			info := &VersionInfo{Version: vers}
+			infos[i] = info
+
+			vcs, resolved, err := fr.retrieveVersion(ctx, client, repo, vers, *cs)
+			if err != nil {
+				info.Error = err.Error()
+				return
+			}
+			info.Resolved = resolved
+			info.CosmosSDKVersion = vcs.CosmosSDKVersion
+			info.TendermintVersion = vcs.TendermintVersion
+			info.IBCVersion = vcs.IBCVersion
+		}(i, vers)
+	}
+	wg.Wait()
+	return infos
+}
@@ -0,0 +1,79 @@
+package chainparse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestChainVersions(t *testing.T) {
+	cb := &Codebase{
+		RecommendedVersion: "v2.0.0",
+		CompatibleVersions: []string{"v1.9.0", "v2.0.0", "", "v1.9.0", "v2.0.1"},
+	}
+	if diff := cmp.Diff(chainVersions(cb), []string{"v2.0.0", "v1.9.0", "v2.0.1"}); diff != "" {
+		t.Fatalf("Versions mismatch: got - want +\n%s", diff)
+	}
+}
+
+func TestRetrieveVersions(t *testing.T) {
+	cst := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/Agoric/ag0/agoric-3.1/go.mod":
+			rw.Write(testdataGoMod)
+		case "/Agoric/ag0/agoric-upgrade-8/go.mod":
+			rw.Write(testdataLatestGoMod)
+		default:
+			http.NotFound(rw, req)
+		}
+	}))
+	defer cst.Close()
+
+	destURL, err := url.Parse(cst.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	art := &alwaysToURLRoundTripper{next: cst.Client(), destURL: destURL}
+	fr := newFetcher(art, WithCompatibleVersions())
+
+	seed := ChainSchema{
+		ChainName:   "agoric",
+		NetworkType: "mainnet",
+		Codebase: &Codebase{
+			GitRepoURL:         "https://github.com/Agoric/ag0/",
+			RecommendedVersion: "agoric-3.1",
+			CompatibleVersions: []string{"agoric-upgrade-8", "agoric-3.1", "agoric-2.0"},
+		},
+	}
+	cs, _, err := fr.run(context.Background(), seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*VersionInfo{
+		{
+			Version:           "agoric-3.1",
+			Recommended:       true,
+			CosmosSDKVersion:  "v0.44.2-alpha.agoric.gaiad.1@github.com/agoric-labs/cosmos-sdk",
+			TendermintVersion: "v0.34.13@github.com/tendermint/tendermint",
+			IBCVersion:        "v1.2.0",
+		},
+		{
+			Version:           "agoric-upgrade-8",
+			CosmosSDKVersion:  "v0.44.2-alpha.agoric.gaiad.1@github.com/agoric-labs/cosmos-sdk",
+			TendermintVersion: "v0.37.13@github.com/tendermint/tendermint",
+			IBCVersion:        "v1.2.0",
+		},
+		{
+			Version: "agoric-2.0",
+			Error:   `https://raw.githubusercontent.com/Agoric/ag0/agoric-2.0/go.mod: HTTP request failed with status: "404 Not Found"`,
+		},
+	}
+	if diff := cmp.Diff(cs.Versions, want); diff != "" {
+		t.Fatalf("Version matrix mismatch: got - want +\n%s", diff)
+	}
+}
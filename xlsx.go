@@ -0,0 +1,349 @@
+package chainparse
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The XLSX workbook is written by hand as a handful of SpreadsheetML parts,
+// which is all that's needed for a few sheets of strings and keeps chainparse
+// free of a spreadsheet library.
+
+type xlsxCell struct {
+	value string
+
+	// link is the URL that the cell hyperlinks to, if any.
+	link string
+
+	// number is set if value should be stored as a number.
+	number bool
+}
+
+// xlsxHighlight highlights the cells of a column for
+// which formula, relative to the first row, is true.
+type xlsxHighlight struct {
+	column  int
+	formula string
+	style   int
+}
+
+type xlsxSheet struct {
+	name       string
+	header     []string
+	widths     []float64
+	rows       [][]xlsxCell
+	highlights []xlsxHighlight
+}
+
+// The cell styles and differential formats defined in xlsxStylesXML.
+const (
+	xlsxStyleHeader = 1
+	xlsxStyleLink   = 2
+
+	xlsxHighlightOutdated = 0
+	xlsxHighlightForked   = 1
+)
+
+// xlsxColumn returns the column's letters in the A1 notation for the zero based index i.
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// xmlEscape escapes s as XML character data or attribute value.
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func (sheet *xlsxSheet) xml() (sheetXML, relsXML string) {
+	var b, rels strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`)
+	// Freeze the header so that it stays put while scrolling.
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	b.WriteString(`<cols>`)
+	for i, width := range sheet.widths {
+		fmt.Fprintf(&b, `<col min="%d" max="%d" width="%g" customWidth="1"/>`, i+1, i+1, width)
+	}
+	b.WriteString(`</cols><sheetData>`)
+
+	var links []string
+	writeRow := func(r int, cells []xlsxCell, style int) {
+		fmt.Fprintf(&b, `<row r="%d">`, r)
+		for c, cell := range cells {
+			ref := xlsxColumn(c) + strconv.Itoa(r)
+			cellStyle := style
+			if cell.link != "" {
+				cellStyle = xlsxStyleLink
+				links = append(links, fmt.Sprintf(`<hyperlink ref="%s" r:id="rId%d"/>`, ref, len(links)+1))
+				fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="%s" TargetMode="External"/>`,
+					len(links), xmlEscape(cell.link))
+			}
+			styleAttr := ""
+			if cellStyle != 0 {
+				styleAttr = fmt.Sprintf(` s="%d"`, cellStyle)
+			}
+			switch {
+			case cell.value == "" && cellStyle == 0:
+			case cell.number:
+				fmt.Fprintf(&b, `<c r="%s"%s><v>%s</v></c>`, ref, styleAttr, xmlEscape(cell.value))
+			default:
+				fmt.Fprintf(&b, `<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, styleAttr, xmlEscape(cell.value))
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+
+	header := make([]xlsxCell, len(sheet.header))
+	for i, title := range sheet.header {
+		header[i] = xlsxCell{value: title}
+	}
+	writeRow(1, header, xlsxStyleHeader)
+	for i, row := range sheet.rows {
+		writeRow(i+2, row, 0)
+	}
+	b.WriteString(`</sheetData>`)
+
+	lastRow := len(sheet.rows) + 1
+	lastCol := xlsxColumn(len(sheet.header) - 1)
+	fmt.Fprintf(&b, `<autoFilter ref="A1:%s%d"/>`, lastCol, lastRow)
+	if len(sheet.rows) != 0 {
+		for i, hl := range sheet.highlights {
+			col := xlsxColumn(hl.column)
+			fmt.Fprintf(&b, `<conditionalFormatting sqref="%s2:%s%d"><cfRule type="expression" dxfId="%d" priority="%d"><formula>%s</formula></cfRule></conditionalFormatting>`,
+				col, col, lastRow, hl.style, i+1, xmlEscape(hl.formula))
+		}
+	}
+	if len(links) != 0 {
+		b.WriteString(`<hyperlinks>` + strings.Join(links, "") + `</hyperlinks>`)
+	}
+	b.WriteString(`</worksheet>`)
+
+	if len(links) == 0 {
+		return b.String(), ""
+	}
+	return b.String(), xml.Header +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` + rels.String() + `</Relationships>`
+}
+
+const xlsxStylesXML = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="3">` +
+	`<font><sz val="11"/><name val="Calibri"/></font>` +
+	`<font><b/><sz val="11"/><name val="Calibri"/></font>` +
+	`<font><u/><sz val="11"/><color rgb="FF0563C1"/><name val="Calibri"/></font>` +
+	`</fonts>` +
+	`<fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill>` +
+	`<fill><patternFill patternType="solid"><fgColor rgb="FFF3F3F3"/><bgColor indexed="64"/></patternFill></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="3">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="2" borderId="0" xfId="0" applyFont="1" applyFill="1"/>` +
+	`<xf numFmtId="0" fontId="2" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`<dxfs count="2">` +
+	`<dxf><font><color rgb="FF9C5700"/></font><fill><patternFill><bgColor rgb="FFFFEB9C"/></patternFill></fill></dxf>` +
+	`<dxf><font><color rgb="FF3F2A84"/></font><fill><patternFill><bgColor rgb="FFE8E0FF"/></patternFill></fill></dxf>` +
+	`</dxfs>` +
+	`</styleSheet>`
+
+// The names of the tracked dependencies as listed in the Outdated and Forked columns.
+var xlsxDependencyNames = map[string]string{
+	moduleCosmosSDK:  "Cosmos SDK",
+	moduleTendermint: "Tendermint",
+	moduleIBC:        "IBC",
+}
+
+func dependencyNames(row *ReportRow, flagged func(ReportDependency) bool) string {
+	var names []string
+	for _, dep := range []struct {
+		module string
+		rd     ReportDependency
+	}{
+		{moduleCosmosSDK, row.CosmosSDK},
+		{moduleTendermint, row.Tendermint},
+		{moduleIBC, row.IBC},
+	} {
+		if flagged(dep.rd) {
+			names = append(names, xlsxDependencyNames[dep.module])
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// projectsSheet lays out chains like the Projects sheet of the
+// spreadsheet, with the Outdated and Forked columns appended.
+func projectsSheet(name string, chains []*ChainSchema) *xlsxSheet {
+	rep := NewReport(&Result{Chains: chains}, time.Time{})
+	rows := append(append([]*ReportRow(nil), rep.Mainnets...), rep.Testnets...)
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].Chain.ChainName < rows[j].Chain.ChainName
+	})
+
+	sheet := &xlsxSheet{
+		name:   name,
+		widths: []float64{22, 45, 20, 20, 11, 24, 40, 30, 24, 24, 24},
+	}
+	for _, col := range DefaultColumns {
+		sheet.header = append(sheet.header, col.Title())
+	}
+	sheet.header = append(sheet.header, "Outdated", "Forked")
+
+	for _, row := range rows {
+		cells := make([]xlsxCell, 0, len(sheet.header))
+		for _, col := range DefaultColumns {
+			cell := xlsxCell{value: col.Value(row.Chain)}
+			if col.Path == "codebase.git_repo" {
+				cell.link = strings.TrimSpace(cell.value)
+				if row.Chain.Repo != nil {
+					cell.link = row.Chain.Repo.URL()
+				}
+			}
+			cells = append(cells, cell)
+		}
+		cells = append(cells,
+			xlsxCell{value: dependencyNames(row, func(rd ReportDependency) bool { return rd.Outdated })},
+			xlsxCell{value: dependencyNames(row, func(rd ReportDependency) bool { return rd.Forked })},
+		)
+		sheet.rows = append(sheet.rows, cells)
+	}
+
+	// Highlight the version cells by what the Outdated and Forked columns
+	// say, so that the formatting follows any edits made to them.
+	outdatedCol, forkedCol := xlsxColumn(len(DefaultColumns)), xlsxColumn(len(DefaultColumns)+1)
+	for i, col := range DefaultColumns {
+		var module string
+		switch col.Path {
+		case "cosmos_sdk_version":
+			module = moduleCosmosSDK
+		case "tendermint_version":
+			module = moduleTendermint
+		case "ibc_version":
+			module = moduleIBC
+		default:
+			continue
+		}
+		dep := xlsxDependencyNames[module]
+		sheet.highlights = append(sheet.highlights,
+			xlsxHighlight{column: i, style: xlsxHighlightOutdated, formula: fmt.Sprintf(`ISNUMBER(SEARCH("%s",$%s2))`, dep, outdatedCol)},
+			xlsxHighlight{column: i, style: xlsxHighlightForked, formula: fmt.Sprintf(`ISNUMBER(SEARCH("%s",$%s2))`, dep, forkedCol)},
+		)
+	}
+	return sheet
+}
+
+func failuresSheet(res *Result) *xlsxSheet {
+	sheet := &xlsxSheet{
+		name:   "Failures",
+		header: []string{"Chain", "Path", "Category", "HTTP status", "URL", "Message"},
+		widths: []float64{22, 40, 18, 12, 60, 80},
+	}
+	for _, out := range res.Failures() {
+		status := xlsxCell{}
+		if out.HTTPStatus != 0 {
+			status = xlsxCell{value: strconv.Itoa(out.HTTPStatus), number: true}
+		}
+		sheet.rows = append(sheet.rows, []xlsxCell{
+			{value: out.ChainName},
+			{value: out.Path},
+			{value: string(out.Category)},
+			status,
+			{value: out.URL, link: out.URL},
+			{value: out.Message},
+		})
+	}
+	return sheet
+}
+
+// WriteXLSX writes res as an XLSX workbook with the sheets of the chainparse
+// spreadsheet: "Projects" with every chain as of its recommended version,
+// "LatestProjects" with every chain as of the latest analysis, and "Failures".
+// Chains whose latest analysis matched the recommended version, or that had
+// none, are listed in LatestProjects as they are in Projects.
+func WriteXLSX(w io.Writer, res *Result) error {
+	latest := make([]*ChainSchema, 0, len(res.Chains))
+	for _, cs := range res.Chains {
+		if cs.Latest != nil {
+			latest = append(latest, cs.Latest)
+		} else {
+			latest = append(latest, cs)
+		}
+	}
+	sheets := []*xlsxSheet{
+		projectsSheet("Projects", res.Chains),
+		projectsSheet("LatestProjects", latest),
+		failuresSheet(res),
+	}
+
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes(len(sheets))},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/styles.xml", xlsxStylesXML},
+	}
+
+	var workbook, workbookRels strings.Builder
+	workbook.WriteString(xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	workbookRels.WriteString(xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	var definedNames strings.Builder
+	for i, sheet := range sheets {
+		n := i + 1
+		fmt.Fprintf(&workbook, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(sheet.name), n, n)
+		fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)
+		// Excel expects a hidden name for every sheet's autoFilter.
+		fmt.Fprintf(&definedNames, `<definedName name="_xlnm._FilterDatabase" localSheetId="%d" hidden="1">'%s'!$A$1:$%s$%d</definedName>`,
+			i, xmlEscape(sheet.name), xlsxColumn(len(sheet.header)-1), len(sheet.rows)+1)
+
+		sheetXML, relsXML := sheet.xml()
+		parts = append(parts, struct{ name, body string }{fmt.Sprintf("xl/worksheets/sheet%d.xml", n), sheetXML})
+		if relsXML != "" {
+			parts = append(parts, struct{ name, body string }{fmt.Sprintf("xl/worksheets/_rels/sheet%d.xml.rels", n), relsXML})
+		}
+	}
+	workbook.WriteString(`</sheets><definedNames>` + definedNames.String() + `</definedNames></workbook>`)
+	fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(sheets)+1)
+	workbookRels.WriteString(`</Relationships>`)
+	parts = append(parts,
+		struct{ name, body string }{"xl/workbook.xml", workbook.String()},
+		struct{ name, body string }{"xl/_rels/workbook.xml.rels", workbookRels.String()},
+	)
+
+	zw := zip.NewWriter(w)
+	for _, part := range parts {
+		fw, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, part.body); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func xlsxContentTypes(nSheets int) string {
+	var b strings.Builder
+	b.WriteString(xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for n := 1; n <= nSheets; n++ {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)
+	}
+	b.WriteString(`</Types>`)
+	return b.String()
+}
@@ -0,0 +1,169 @@
+package chainparse
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type xlsxTestSheet struct {
+	Pane struct {
+		YSplit int    `xml:"ySplit,attr"`
+		State  string `xml:"state,attr"`
+	} `xml:"sheetViews>sheetView>pane"`
+	Rows []struct {
+		Cells []struct {
+			Ref    string `xml:"r,attr"`
+			Inline string `xml:"is>t"`
+			Value  string `xml:"v"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+	ConditionalFormatting []struct {
+		Sqref   string `xml:"sqref,attr"`
+		Formula string `xml:"cfRule>formula"`
+	} `xml:"conditionalFormatting"`
+	Hyperlinks []struct {
+		Ref string `xml:"ref,attr"`
+		ID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"hyperlinks>hyperlink"`
+}
+
+func (sheet *xlsxTestSheet) values() map[string]string {
+	values := make(map[string]string)
+	for _, row := range sheet.Rows {
+		for _, c := range row.Cells {
+			values[c.Ref] = c.Inline + c.Value
+		}
+	}
+	return values
+}
+
+func TestWriteXLSX(t *testing.T) {
+	res := &Result{Outcomes: reportResult.Outcomes}
+	for _, cs := range reportResult.Chains {
+		cs := *cs
+		if cs.ChainName == "cosmoshub" {
+			latest := cs
+			latest.CosmosSDKVersion = "v0.46.0"
+			cs.Latest = &latest
+		}
+		res.Chains = append(res.Chains, &cs)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteXLSX(&buf, res); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		blob, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Every part must be well formed.
+		dec := xml.NewDecoder(bytes.NewReader(blob))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: %v", f.Name, err)
+			}
+		}
+		parts[f.Name] = blob
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet3.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("Missing part %q", name)
+		}
+	}
+	workbook := string(parts["xl/workbook.xml"])
+	for _, want := range []string{`<sheet name="Projects" sheetId="1" r:id="rId1"/>`, `<sheet name="LatestProjects" sheetId="2" r:id="rId2"/>`, `<sheet name="Failures" sheetId="3" r:id="rId3"/>`} {
+		if !strings.Contains(workbook, want) {
+			t.Errorf("Workbook is missing %s", want)
+		}
+	}
+
+	sheets := make([]*xlsxTestSheet, 3)
+	for i, name := range []string{"xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml", "xl/worksheets/sheet3.xml"} {
+		sheets[i] = new(xlsxTestSheet)
+		if err := xml.Unmarshal(parts[name], sheets[i]); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if g := sheets[i].Pane; g.YSplit != 1 || g.State != "frozen" {
+			t.Errorf("%s: the header should be frozen, got %+v", name, g)
+		}
+	}
+
+	projects := sheets[0].values()
+	wantProjects := map[string]string{
+		"A1": "Chain", "B1": "Git_Repo", "G1": "CosmosSDK", "J1": "Outdated", "K1": "Forked",
+		"A2": "Agoric", "B2": "https://github.com/Agoric/ag0", "G2": "v0.44.2-alpha.agoric.gaiad.1@github.com/agoric-labs/cosmos-sdk",
+		"J2": "Cosmos SDK, IBC", "K2": "Cosmos SDK",
+		"A3": "Cosmos | Hub", "G3": "v0.45.4",
+		"A4": "<Theta>",
+	}
+	for ref, want := range wantProjects {
+		if g := projects[ref]; g != want {
+			t.Errorf("Projects!%s: got %q, want %q", ref, g, want)
+		}
+	}
+	if _, ok := projects["J3"]; ok {
+		t.Errorf("Projects!J3: the hub isn't outdated, got %q", projects["J3"])
+	}
+	wantFormatting := []string{
+		`G2:G4 ISNUMBER(SEARCH("Cosmos SDK",$J2))`, `G2:G4 ISNUMBER(SEARCH("Cosmos SDK",$K2))`,
+		`H2:H4 ISNUMBER(SEARCH("Tendermint",$J2))`, `H2:H4 ISNUMBER(SEARCH("Tendermint",$K2))`,
+		`I2:I4 ISNUMBER(SEARCH("IBC",$J2))`, `I2:I4 ISNUMBER(SEARCH("IBC",$K2))`,
+	}
+	var gotFormatting []string
+	for _, cf := range sheets[0].ConditionalFormatting {
+		gotFormatting = append(gotFormatting, cf.Sqref+" "+cf.Formula)
+	}
+	if diff := cmp.Diff(gotFormatting, wantFormatting); diff != "" {
+		t.Errorf("Conditional formatting mismatch: got - want +\n%s", diff)
+	}
+	if g, w := len(sheets[0].Hyperlinks), 2; g != w {
+		t.Errorf("Hyperlinks: got %d, want %d", g, w)
+	}
+	if rels := string(parts["xl/worksheets/_rels/sheet1.xml.rels"]); !strings.Contains(rels, `Target="https://github.com/Agoric/ag0" TargetMode="External"`) {
+		t.Errorf("Missing the hyperlink to the repository in %s", rels)
+	}
+
+	latest := sheets[1].values()
+	if g, w := latest["G3"], "v0.46.0"; g != w {
+		t.Errorf("LatestProjects!G3: got %q, want %q", g, w)
+	}
+	if g, w := latest["G2"], projects["G2"]; g != w {
+		t.Errorf("LatestProjects!G2 should fall back to the recommended version: got %q, want %q", g, w)
+	}
+
+	failures := sheets[2].values()
+	wantFailures := map[string]string{"A1": "Chain", "A2": "aioz", "C2": "http", "D2": "404"}
+	for ref, want := range wantFailures {
+		if g := failures[ref]; g != want {
+			t.Errorf("Failures!%s: got %q, want %q", ref, g, want)
+		}
+	}
+}
+
+func TestXLSXColumn(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if g := xlsxColumn(i); g != want {
+			t.Errorf("xlsxColumn(%d): got %q, want %q", i, g, want)
+		}
+	}
+}